@@ -0,0 +1,178 @@
+package gosmparse
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// ApplyOptions configures ApplyChanges.
+type ApplyOptions struct {
+	// ReplicationSequenceNumber is written into the header of the output. If it
+	// is zero, the value of the base file is kept.
+	ReplicationSequenceNumber int64
+	// ReplicationTimestamp is written into the header of the output. If it is
+	// zero, the newest timestamp of all changes is used.
+	ReplicationTimestamp time.Time
+}
+
+// ApplyChanges applies the OsmChange files in changes to the PBF file base and
+// writes the result as PBF to w. Created and modified elements replace older
+// versions of the same element, deleted elements are dropped.
+//
+// base needs to be sorted by type and ID; the output is sorted as well. Change
+// files are applied in the given order and are held in memory, while base is
+// streamed.
+func ApplyChanges(w io.Writer, base io.Reader, changes []io.Reader, opts ApplyOptions) error {
+	cc := &changeCollector{changes: make(map[entityKey]*pendingChange)}
+	for _, r := range changes {
+		if err := NewChangeDecoder(r).Parse(cc); err != nil {
+			return err
+		}
+	}
+
+	a := &changeApplier{
+		enc:     NewEncoder(w),
+		opts:    opts,
+		changes: cc.sorted(),
+		newest:  cc.newest,
+	}
+	dec := NewDecoderWithInfo(base)
+	// A single worker keeps the elements in file order.
+	dec.Workers = 1
+	if err := dec.Parse(a); err != nil {
+		return err
+	}
+	if a.err != nil {
+		return a.err
+	}
+	if err := a.writeChanges(func(entityKey) bool { return true }); err != nil {
+		return err
+	}
+	return a.enc.Close()
+}
+
+type pendingChange struct {
+	key    entityKey
+	action Action
+	entity entity
+}
+
+// changeCollector keeps the newest change of every element.
+type changeCollector struct {
+	action  Action
+	changes map[entityKey]*pendingChange
+	newest  time.Time
+}
+
+func (c *changeCollector) ReadAction(a Action) {
+	c.action = a
+}
+
+func (c *changeCollector) ReadNode(n Node) {
+	c.add(entity{Type: NodeType, Node: n})
+}
+
+func (c *changeCollector) ReadWay(w Way) {
+	c.add(entity{Type: WayType, Way: w})
+}
+
+func (c *changeCollector) ReadRelation(r Relation) {
+	c.add(entity{Type: RelationType, Relation: r})
+}
+
+func (c *changeCollector) add(e entity) {
+	key := entityKey{e.Type, e.element().ID}
+	if existing, ok := c.changes[key]; ok && existing.entity.version() > e.version() {
+		return
+	}
+	c.changes[key] = &pendingChange{key: key, action: c.action, entity: e}
+	if ts := e.element().Info.Timestamp; ts.After(c.newest) {
+		c.newest = ts
+	}
+}
+
+func (c *changeCollector) sorted() []*pendingChange {
+	changes := make([]*pendingChange, 0, len(c.changes))
+	for _, pc := range c.changes {
+		changes = append(changes, pc)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].key.less(changes[j].key)
+	})
+	return changes
+}
+
+// changeApplier merges the sorted changes into the stream of base elements.
+type changeApplier struct {
+	enc     *Encoder
+	opts    ApplyOptions
+	changes []*pendingChange
+	pos     int
+	newest  time.Time
+	err     error
+}
+
+func (a *changeApplier) ReadHeader(h Header) {
+	if a.opts.ReplicationSequenceNumber != 0 {
+		h.ReplicationSequenceNumber = a.opts.ReplicationSequenceNumber
+	}
+	switch {
+	case !a.opts.ReplicationTimestamp.IsZero():
+		h.ReplicationTimestamp = a.opts.ReplicationTimestamp
+	case !a.newest.IsZero():
+		h.ReplicationTimestamp = a.newest
+	}
+	a.enc.Header = h
+}
+
+func (a *changeApplier) ReadNode(n Node) {
+	a.read(entity{Type: NodeType, Node: n})
+}
+
+func (a *changeApplier) ReadWay(w Way) {
+	a.read(entity{Type: WayType, Way: w})
+}
+
+func (a *changeApplier) ReadRelation(r Relation) {
+	a.read(entity{Type: RelationType, Relation: r})
+}
+
+func (a *changeApplier) read(e entity) {
+	if a.err != nil {
+		return
+	}
+	key := entityKey{e.Type, e.element().ID}
+	a.err = a.writeChanges(func(k entityKey) bool { return k.less(key) })
+	if a.err != nil {
+		return
+	}
+	if a.pos < len(a.changes) && a.changes[a.pos].key == key {
+		pc := a.changes[a.pos]
+		a.pos++
+		// Changes that are older than the base element are outdated.
+		if pc.entity.version() >= e.version() {
+			a.err = a.writeChange(pc)
+			return
+		}
+	}
+	a.err = e.write(a.enc)
+}
+
+// writeChanges writes all pending changes as long as before returns true.
+func (a *changeApplier) writeChanges(before func(entityKey) bool) error {
+	for a.pos < len(a.changes) && before(a.changes[a.pos].key) {
+		if err := a.writeChange(a.changes[a.pos]); err != nil {
+			return err
+		}
+		a.pos++
+	}
+	return nil
+}
+
+func (a *changeApplier) writeChange(pc *pendingChange) error {
+	if pc.action == ActionDelete {
+		return nil
+	}
+	return pc.entity.write(a.enc)
+}
@@ -0,0 +1,90 @@
+package gosmparse
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyChanges(t *testing.T) {
+	base, err := os.Open("testdata/base.pbf")
+	assert.Nil(t, err)
+	defer base.Close()
+	change, err := os.Open("testdata/change.osc.gz")
+	assert.Nil(t, err)
+	defer change.Close()
+
+	var buf bytes.Buffer
+	err = ApplyChanges(&buf, base, []io.Reader{change}, ApplyOptions{ReplicationSequenceNumber: 1234})
+	assert.Nil(t, err)
+
+	out := &headerCachedReader{}
+	dec := NewDecoderWithInfo(&buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(out))
+
+	assert.Equal(t, int64(1234), out.Header.ReplicationSequenceNumber)
+	assert.True(t, out.Header.ReplicationTimestamp.Equal(time.Date(2019, 4, 1, 19, 0, 0, 0, time.UTC)))
+
+	assert.Len(t, out.Nodes, 2)
+	assert.Equal(t, int64(1), out.Nodes[0].ID)
+	assert.Equal(t, 2, out.Nodes[0].Info.Version)
+	assert.InDelta(t, 0.0015, out.Nodes[0].Lat, 1e-9)
+	assert.Equal(t, int64(3), out.Nodes[1].ID)
+	assert.Equal(t, map[string]string{"amenity": "bench"}, out.Nodes[1].Tags)
+
+	assert.Len(t, out.Ways, 1)
+	assert.Equal(t, []int64{1, 3}, out.Ways[0].NodeIDs)
+	assert.Equal(t, "Another User", out.Ways[0].Info.User)
+
+	assert.Len(t, out.Rels, 0)
+}
+
+func TestApplyOutdatedChange(t *testing.T) {
+	base, err := os.Open("testdata/base.pbf")
+	assert.Nil(t, err)
+	defer base.Close()
+
+	osc := `<osmChange version="0.6"><modify>
+		<node id="2" lat="1" lon="1" version="1" timestamp="2014-01-01T00:00:00Z"/>
+		<node id="2" lat="2" lon="2" version="0" timestamp="2013-01-01T00:00:00Z"/>
+	</modify></osmChange>`
+	var buf bytes.Buffer
+	err = ApplyChanges(&buf, base, []io.Reader{bytes.NewBufferString(osc)}, ApplyOptions{})
+	assert.Nil(t, err)
+
+	out := &cachedReader{}
+	dec := NewDecoder(&buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(out))
+	assert.Len(t, out.Nodes, 2)
+	assert.InDelta(t, 1, out.Nodes[1].Lat, 1e-9)
+}
+
+func TestApplyChangesWithoutMetadata(t *testing.T) {
+	base := encodePBF(t, Header{}, &cachedReader{
+		Nodes: []Node{{Element: Element{ID: 1}, Lat: 1}, {Element: Element{ID: 2}, Lat: 2}},
+		Ways:  []Way{{Element: Element{ID: 1}, NodeIDs: []int64{1, 2}}},
+	})
+	osc := `<osmChange version="0.6"><modify>
+		<node id="2" lat="3" lon="3" version="2" timestamp="2019-01-01T00:00:00Z"/>
+	</modify></osmChange>`
+	var buf bytes.Buffer
+	err := ApplyChanges(&buf, base, []io.Reader{bytes.NewBufferString(osc)}, ApplyOptions{})
+	assert.Nil(t, err)
+
+	out := &cachedReader{}
+	dec := NewDecoderWithInfo(&buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(out))
+	assert.Len(t, out.Nodes, 2)
+	assert.InDelta(t, 1, out.Nodes[0].Lat, 1e-9)
+	assert.Nil(t, out.Nodes[0].Info)
+	assert.InDelta(t, 3, out.Nodes[1].Lat, 1e-9)
+	assert.Equal(t, 2, out.Nodes[1].Info.Version)
+	assert.Len(t, out.Ways, 1)
+}
@@ -0,0 +1,446 @@
+package gosmparse
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// A Ring is a closed sequence of locations; the first and the last location
+// are the same.
+type Ring []LatLon
+
+// A Polygon consists of an outer ring and any number of inner rings (holes).
+// Outer rings are oriented counterclockwise, inner rings clockwise.
+type Polygon struct {
+	Outer  Ring
+	Inners []Ring
+}
+
+// A MultiPolygon is a set of polygons, as assembled from a multipolygon or
+// boundary relation.
+type MultiPolygon []Polygon
+
+// AreaProblemType describes the kind of problem that occurred while assembling
+// an area.
+type AreaProblemType int
+
+const (
+	// MissingMember means that a member way was not available.
+	MissingMember AreaProblemType = iota
+	// MissingLocation means that a member way has no (or incomplete)
+	// coordinates. NodeID refers to the first node without a location, if
+	// the way has coordinates at all.
+	MissingLocation
+	// UnclosedRing means that the ways could not be joined into a closed
+	// ring. NodeID and Location refer to one of the open ends.
+	UnclosedRing
+	// SelfIntersection means that two segments of the rings intersect.
+	// Location is the intersection point.
+	SelfIntersection
+	// RoleMismatch means that the role of a member way does not match the
+	// ring it has been assembled into. This is not fatal.
+	RoleMismatch
+	// NoMemberWays means that the relation has no member ways with an empty,
+	// "outer" or "inner" role.
+	NoMemberWays
+	// DegenerateWay means that a member way has fewer than two nodes.
+	DegenerateWay
+)
+
+// String returns a short description of the problem type.
+func (t AreaProblemType) String() string {
+	switch t {
+	case MissingMember:
+		return "missing member"
+	case MissingLocation:
+		return "missing location"
+	case UnclosedRing:
+		return "unclosed ring"
+	case SelfIntersection:
+		return "self-intersection"
+	case RoleMismatch:
+		return "role mismatch"
+	case NoMemberWays:
+		return "no member ways"
+	case DegenerateWay:
+		return "degenerate way"
+	}
+	return fmt.Sprintf("AreaProblemType(%d)", int(t))
+}
+
+// An AreaProblem describes a problem that occurred while assembling the area of
+// a relation. Fields that do not apply to the problem type are zero.
+type AreaProblem struct {
+	Type       AreaProblemType
+	RelationID int64
+	WayID      int64
+	NodeID     int64
+	Location   LatLon
+}
+
+func (p AreaProblem) String() string {
+	return fmt.Sprintf("relation %d: %v (way %d, node %d, location %v)", p.RelationID, p.Type, p.WayID, p.NodeID, p.Location)
+}
+
+// IsAreaRelation reports whether r is a multipolygon or boundary relation.
+func IsAreaRelation(r Relation) bool {
+	t := r.Tags["type"]
+	return t == "multipolygon" || t == "boundary"
+}
+
+// AssembleArea builds the polygons of a multipolygon or boundary relation from
+// its member ways, which are looked up in ways and need to have their Coords
+// populated (e.g. by LocationDecoder). Members that are not ways or whose role
+// is neither empty, "outer" nor "inner" are ignored.
+//
+// Ways are joined into closed rings by their end nodes, so a ring may consist
+// of any number of ways. Whether a ring is an outer or an inner ring is
+// determined by containment; the roles are only checked for consistency.
+//
+// All problems are reported. If any of them is fatal (i.e. not RoleMismatch),
+// the returned MultiPolygon is nil.
+func AssembleArea(rel Relation, ways map[int64]Way) (MultiPolygon, []AreaProblem) {
+	var (
+		problems []AreaProblem
+		segs     []*ringWay
+		seen     = map[int64]bool{}
+	)
+	for _, m := range rel.Members {
+		if m.Type != WayType || (m.Role != "" && m.Role != "outer" && m.Role != "inner") {
+			continue
+		}
+		if seen[m.ID] {
+			continue
+		}
+		seen[m.ID] = true
+		w, ok := ways[m.ID]
+		if !ok {
+			problems = append(problems, AreaProblem{Type: MissingMember, RelationID: rel.ID, WayID: m.ID})
+			continue
+		}
+		if len(w.NodeIDs) < 2 {
+			problems = append(problems, AreaProblem{Type: DegenerateWay, RelationID: rel.ID, WayID: w.ID})
+			continue
+		}
+		if !w.CoordsComplete() {
+			p := AreaProblem{Type: MissingLocation, RelationID: rel.ID, WayID: w.ID}
+			for i, ll := range w.Coords {
+				if ll.Missing() {
+					p.NodeID = w.NodeIDs[i]
+					break
+				}
+			}
+			problems = append(problems, p)
+			continue
+		}
+		segs = append(segs, &ringWay{way: w, role: m.Role})
+	}
+	if len(problems) != 0 {
+		return nil, problems
+	}
+	if len(segs) == 0 {
+		return nil, []AreaProblem{{Type: NoMemberWays, RelationID: rel.ID}}
+	}
+
+	rings, ringProblems := joinRings(rel.ID, segs)
+	problems = append(problems, ringProblems...)
+	if len(problems) != 0 {
+		return nil, problems
+	}
+	problems = append(problems, findIntersections(rel.ID, rings)...)
+	if len(problems) != 0 {
+		return nil, problems
+	}
+
+	mp, roleProblems := classifyRings(rel.ID, rings)
+	return mp, roleProblems
+}
+
+// ringWay is a member way that is used to build a ring.
+type ringWay struct {
+	way  Way
+	role string
+	used bool
+}
+
+// assembledRing is a closed ring together with the ways it consists of.
+type assembledRing struct {
+	nodes  []int64
+	coords Ring
+	ways   []*ringWay
+	area   float64
+}
+
+// joinRings combines the ways into closed rings by connecting their end nodes.
+func joinRings(relID int64, segs []*ringWay) ([]*assembledRing, []AreaProblem) {
+	// index of all ways by their end nodes
+	ends := map[int64][]*ringWay{}
+	for _, s := range segs {
+		ids := s.way.NodeIDs
+		ends[ids[0]] = append(ends[ids[0]], s)
+		ends[ids[len(ids)-1]] = append(ends[ids[len(ids)-1]], s)
+	}
+
+	var (
+		rings    []*assembledRing
+		problems []AreaProblem
+	)
+	for _, start := range segs {
+		if start.used {
+			continue
+		}
+		start.used = true
+		r := &assembledRing{
+			nodes:  append([]int64(nil), start.way.NodeIDs...),
+			coords: append(Ring(nil), start.way.Coords...),
+			ways:   []*ringWay{start},
+		}
+		for r.nodes[0] != r.nodes[len(r.nodes)-1] {
+			last := r.nodes[len(r.nodes)-1]
+			next := nextRingWay(ends[last])
+			if next == nil {
+				problems = append(problems,
+					AreaProblem{Type: UnclosedRing, RelationID: relID, WayID: r.ways[0].way.ID, NodeID: r.nodes[0], Location: r.coords[0]},
+					AreaProblem{Type: UnclosedRing, RelationID: relID, WayID: r.ways[len(r.ways)-1].way.ID, NodeID: last, Location: r.coords[len(r.coords)-1]},
+				)
+				break
+			}
+			next.used = true
+			ids, coords := next.way.NodeIDs, next.way.Coords
+			if ids[0] != last {
+				ids, coords = reverseIDs(ids), reverseRing(coords)
+			}
+			r.nodes = append(r.nodes, ids[1:]...)
+			r.coords = append(r.coords, coords[1:]...)
+			r.ways = append(r.ways, next)
+		}
+		if r.nodes[0] != r.nodes[len(r.nodes)-1] {
+			continue
+		}
+		if len(r.nodes) < 4 {
+			problems = append(problems, AreaProblem{Type: UnclosedRing, RelationID: relID, WayID: r.ways[0].way.ID, NodeID: r.nodes[0], Location: r.coords[0]})
+			continue
+		}
+		r.area = signedArea(r.coords)
+		rings = append(rings, r)
+	}
+	return rings, problems
+}
+
+func nextRingWay(candidates []*ringWay) *ringWay {
+	for _, c := range candidates {
+		if !c.used {
+			return c
+		}
+	}
+	return nil
+}
+
+func reverseIDs(ids []int64) []int64 {
+	out := make([]int64, len(ids))
+	for i, id := range ids {
+		out[len(ids)-1-i] = id
+	}
+	return out
+}
+
+func reverseRing(r Ring) Ring {
+	out := make(Ring, len(r))
+	for i, ll := range r {
+		out[len(r)-1-i] = ll
+	}
+	return out
+}
+
+// signedArea returns the area of the ring in square degrees, positive if the
+// ring is oriented counterclockwise.
+func signedArea(r Ring) float64 {
+	var a float64
+	for i := 0; i < len(r)-1; i++ {
+		a += r[i].Lon*r[i+1].Lat - r[i+1].Lon*r[i].Lat
+	}
+	return a / 2
+}
+
+type segment struct {
+	a, b       LatLon
+	ring, pos  int
+	minX, maxX float64
+}
+
+// findIntersections reports all crossings between segments of the rings. Rings
+// may touch at their nodes, but must not cross.
+func findIntersections(relID int64, rings []*assembledRing) []AreaProblem {
+	var segs []segment
+	for ri, r := range rings {
+		for i := 0; i < len(r.coords)-1; i++ {
+			a, b := r.coords[i], r.coords[i+1]
+			segs = append(segs, segment{a: a, b: b, ring: ri, pos: i, minX: math.Min(a.Lon, b.Lon), maxX: math.Max(a.Lon, b.Lon)})
+		}
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].minX < segs[j].minX })
+
+	var problems []AreaProblem
+	for i := range segs {
+		for j := i + 1; j < len(segs) && segs[j].minX <= segs[i].maxX; j++ {
+			s1, s2 := &segs[i], &segs[j]
+			if s1.ring == s2.ring && adjacentSegments(s1.pos, s2.pos, len(rings[s1.ring].coords)-1) {
+				continue
+			}
+			if p, ok := crossing(s1.a, s1.b, s2.a, s2.b); ok {
+				problems = append(problems, AreaProblem{Type: SelfIntersection, RelationID: relID, Location: p})
+			}
+		}
+	}
+	return problems
+}
+
+func adjacentSegments(i, j, n int) bool {
+	d := i - j
+	if d < 0 {
+		d = -d
+	}
+	return d <= 1 || d == n-1
+}
+
+// crossing returns the intersection point of the segments a-b and c-d, if they
+// cross or overlap. Segments that only touch at one of their end points are
+// not considered crossing.
+func crossing(a, b, c, d LatLon) (LatLon, bool) {
+	d1 := orientation(c, d, a)
+	d2 := orientation(c, d, b)
+	d3 := orientation(a, b, c)
+	d4 := orientation(a, b, d)
+
+	if d1 == 0 && d2 == 0 {
+		// collinear: overlapping segments share more than a single point
+		if overlap(a, b, c, d) {
+			return a, true
+		}
+		return LatLon{}, false
+	}
+	if d1 == 0 || d2 == 0 || d3 == 0 || d4 == 0 {
+		return LatLon{}, false
+	}
+	if (d1 > 0) != (d2 > 0) && (d3 > 0) != (d4 > 0) {
+		t := d1 / (d1 - d2)
+		return LatLon{Lat: a.Lat + t*(b.Lat-a.Lat), Lon: a.Lon + t*(b.Lon-a.Lon)}, true
+	}
+	return LatLon{}, false
+}
+
+func orientation(a, b, c LatLon) float64 {
+	return (b.Lon-a.Lon)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lon-a.Lon)
+}
+
+func overlap(a, b, c, d LatLon) bool {
+	key := func(ll LatLon) float64 { return ll.Lon }
+	if a.Lon == b.Lon {
+		key = func(ll LatLon) float64 { return ll.Lat }
+	}
+	lo1, hi1 := math.Min(key(a), key(b)), math.Max(key(a), key(b))
+	lo2, hi2 := math.Min(key(c), key(d)), math.Max(key(c), key(d))
+	return math.Min(hi1, hi2) > math.Max(lo1, lo2)
+}
+
+// classifyRings determines for every ring how many other rings contain it.
+// Rings at an even depth are outer rings, the others are inner rings of the
+// smallest outer ring containing them.
+func classifyRings(relID int64, rings []*assembledRing) (MultiPolygon, []AreaProblem) {
+	// sort by size, so that containing rings come first
+	sort.Slice(rings, func(i, j int) bool { return math.Abs(rings[i].area) > math.Abs(rings[j].area) })
+
+	var (
+		problems []AreaProblem
+		mp       MultiPolygon
+		depth    = make([]int, len(rings))
+		parent   = make([]int, len(rings))
+		polyOf   = make([]int, len(rings))
+	)
+	for i, r := range rings {
+		parent[i] = -1
+		probe := ringProbe(r.coords)
+		for j := i - 1; j >= 0; j-- {
+			if pointInRing(probe, rings[j].coords) {
+				parent[i] = j
+				depth[i] = depth[j] + 1
+				break
+			}
+		}
+
+		inner := depth[i]%2 == 1
+		role := "outer"
+		if inner {
+			role = "inner"
+		}
+		for _, w := range r.ways {
+			if w.role != "" && w.role != role {
+				problems = append(problems, AreaProblem{Type: RoleMismatch, RelationID: relID, WayID: w.way.ID})
+			}
+		}
+
+		if inner {
+			ring := r.coords
+			if r.area > 0 {
+				ring = reverseRing(ring)
+			}
+			p := &mp[polyOf[parent[i]]]
+			p.Inners = append(p.Inners, ring)
+			continue
+		}
+		ring := r.coords
+		if r.area < 0 {
+			ring = reverseRing(ring)
+		}
+		polyOf[i] = len(mp)
+		mp = append(mp, Polygon{Outer: ring})
+	}
+	return mp, problems
+}
+
+// ringProbe returns a point strictly inside the ring's area, which is used to
+// test the containment of the whole ring. Points on the ring itself would be
+// ambiguous for rings that touch each other.
+//
+// The probe lies on a horizontal line between the two lowest latitudes of the
+// ring, which does not run through any vertex, halfway between the first two
+// crossings of that line with the ring.
+func ringProbe(r Ring) LatLon {
+	lo, next := math.Inf(1), math.Inf(1)
+	for _, ll := range r {
+		lo = math.Min(lo, ll.Lat)
+	}
+	for _, ll := range r {
+		if ll.Lat > lo && ll.Lat < next {
+			next = ll.Lat
+		}
+	}
+	if math.IsInf(next, 1) {
+		// degenerate ring without area
+		return r[0]
+	}
+	lat := (lo + next) / 2
+	var lons []float64
+	for i := 1; i < len(r); i++ {
+		a, b := r[i-1], r[i]
+		if (a.Lat > lat) != (b.Lat > lat) {
+			lons = append(lons, a.Lon+(lat-a.Lat)*(b.Lon-a.Lon)/(b.Lat-a.Lat))
+		}
+	}
+	sort.Float64s(lons)
+	return LatLon{Lat: lat, Lon: (lons[0] + lons[1]) / 2}
+}
+
+// pointInRing tests with the even-odd rule whether p lies inside r.
+func pointInRing(p LatLon, r Ring) bool {
+	in := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,177 @@
+package gosmparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testWay creates a way with the given node IDs, where the location of each
+// node is taken from coords.
+func testWay(id int64, coords map[int64]LatLon, nodes ...int64) Way {
+	w := Way{Element: Element{ID: id}, NodeIDs: nodes}
+	for _, n := range nodes {
+		w.Coords = append(w.Coords, coords[n])
+	}
+	return w
+}
+
+var areaCoords = map[int64]LatLon{
+	// outer square 0..10
+	1: {0, 0}, 2: {0, 10}, 3: {10, 10}, 4: {10, 0},
+	// hole 2..8
+	5: {2, 2}, 6: {8, 2}, 7: {8, 8}, 8: {2, 8},
+	// island in the hole 4..6
+	9: {4, 4}, 10: {4, 6}, 11: {6, 6}, 12: {6, 4},
+	// separate square 20..21
+	13: {20, 20}, 14: {20, 21}, 15: {21, 21}, 16: {21, 20},
+}
+
+func multipolygon(members ...RelationMember) Relation {
+	return Relation{
+		Element: Element{ID: 99, Tags: map[string]string{"type": "multipolygon"}},
+		Members: members,
+	}
+}
+
+func TestAssembleArea(t *testing.T) {
+	ways := map[int64]Way{
+		// outer ring split into two ways, one of them reversed
+		1: testWay(1, areaCoords, 1, 2, 3),
+		2: testWay(2, areaCoords, 1, 4, 3),
+		3: testWay(3, areaCoords, 5, 6, 7, 8, 5),
+		4: testWay(4, areaCoords, 9, 10, 11, 12, 9),
+		5: testWay(5, areaCoords, 13, 14, 15, 16, 13),
+	}
+	rel := multipolygon(
+		RelationMember{ID: 3, Type: WayType, Role: "inner"},
+		RelationMember{ID: 1, Type: WayType, Role: "outer"},
+		RelationMember{ID: 4, Type: WayType, Role: "outer"},
+		RelationMember{ID: 2, Type: WayType, Role: "outer"},
+		RelationMember{ID: 5, Type: WayType, Role: ""},
+		RelationMember{ID: 1, Type: NodeType, Role: "label"},
+	)
+	assert.True(t, IsAreaRelation(rel))
+
+	mp, problems := AssembleArea(rel, ways)
+	assert.Len(t, problems, 0)
+	assert.Len(t, mp, 3)
+
+	// largest polygon first, with the hole
+	assert.Len(t, mp[0].Outer, 5)
+	assert.True(t, signedArea(mp[0].Outer) > 0)
+	assert.InDelta(t, 100, signedArea(mp[0].Outer), 1e-9)
+	assert.Len(t, mp[0].Inners, 1)
+	assert.True(t, signedArea(mp[0].Inners[0]) < 0)
+	assert.InDelta(t, -36, signedArea(mp[0].Inners[0]), 1e-9)
+
+	// island and separate square are outer rings without holes
+	assert.InDelta(t, 4, signedArea(mp[1].Outer), 1e-9)
+	assert.InDelta(t, 1, signedArea(mp[2].Outer), 1e-9)
+	assert.Len(t, mp[1].Inners, 0)
+}
+
+func TestAssembleAreaProblems(t *testing.T) {
+	ways := map[int64]Way{
+		1: testWay(1, areaCoords, 1, 2, 3),
+		2: testWay(2, areaCoords, 1, 2, 4, 3, 1),
+		3: testWay(3, areaCoords, 5, 6, 7, 8, 5),
+		4: {Element: Element{ID: 4}, NodeIDs: []int64{1, 2, 3, 1}},
+		5: testWay(5, areaCoords, 1, 2, 3, 4, 1),
+	}
+
+	_, problems := AssembleArea(multipolygon(RelationMember{ID: 42, Type: WayType, Role: "outer"}), ways)
+	assert.Equal(t, []AreaProblem{{Type: MissingMember, RelationID: 99, WayID: 42}}, problems)
+
+	_, problems = AssembleArea(multipolygon(RelationMember{ID: 4, Type: WayType}), ways)
+	assert.Equal(t, []AreaProblem{{Type: MissingLocation, RelationID: 99, WayID: 4}}, problems)
+
+	// a node that could not be found by Locations
+	store := NewMapLocationStore()
+	for _, id := range []int64{1, 2, 4} {
+		assert.Nil(t, store.Set(id, areaCoords[id]))
+	}
+	incomplete := Way{Element: Element{ID: 6}, NodeIDs: []int64{1, 2, 3, 4, 1}}
+	incomplete.Coords, _, _ = Locations(store, incomplete.NodeIDs)
+	ways[6] = incomplete
+	mp, problems := AssembleArea(multipolygon(RelationMember{ID: 6, Type: WayType}), ways)
+	assert.Nil(t, mp)
+	assert.Equal(t, []AreaProblem{{Type: MissingLocation, RelationID: 99, WayID: 6, NodeID: 3}}, problems)
+
+	mp, problems = AssembleArea(multipolygon(RelationMember{ID: 1, Type: NodeType}), ways)
+	assert.Nil(t, mp)
+	assert.Equal(t, []AreaProblem{{Type: NoMemberWays, RelationID: 99}}, problems)
+
+	ways[7] = testWay(7, areaCoords, 1)
+	mp, problems = AssembleArea(multipolygon(RelationMember{ID: 7, Type: WayType}), ways)
+	assert.Nil(t, mp)
+	assert.Equal(t, []AreaProblem{{Type: DegenerateWay, RelationID: 99, WayID: 7}}, problems)
+
+	_, problems = AssembleArea(multipolygon(RelationMember{ID: 1, Type: WayType}), ways)
+	assert.Len(t, problems, 2)
+	assert.Equal(t, UnclosedRing, problems[0].Type)
+	assert.Equal(t, int64(1), problems[0].NodeID)
+	assert.Equal(t, int64(3), problems[1].NodeID)
+	assert.Equal(t, LatLon{10, 10}, problems[1].Location)
+
+	// bow tie
+	mp, problems = AssembleArea(multipolygon(RelationMember{ID: 2, Type: WayType}), ways)
+	assert.Nil(t, mp)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, SelfIntersection, problems[0].Type)
+	assert.Equal(t, LatLon{5, 5}, problems[0].Location)
+
+	// wrong roles are reported, but the area is built
+	mp, problems = AssembleArea(multipolygon(
+		RelationMember{ID: 5, Type: WayType, Role: "inner"},
+		RelationMember{ID: 3, Type: WayType, Role: "outer"},
+	), ways)
+	assert.Len(t, mp, 1)
+	assert.Len(t, mp[0].Inners, 1)
+	assert.Equal(t, []AreaProblem{
+		{Type: RoleMismatch, RelationID: 99, WayID: 5},
+		{Type: RoleMismatch, RelationID: 99, WayID: 3},
+	}, problems)
+}
+
+func TestAssembleAreaTouchingRings(t *testing.T) {
+	coords := map[int64]LatLon{
+		1: {0, 0}, 2: {0, 10}, 3: {10, 10}, 4: {10, 0},
+		// hole touching the outer ring at node 1
+		5: {5, 2}, 6: {2, 5},
+		// square next to the outer ring, touching it at node 3
+		7: {10, 20}, 8: {20, 20}, 9: {20, 10},
+	}
+	ways := map[int64]Way{
+		1: testWay(1, coords, 1, 2, 3, 4, 1),
+		2: testWay(2, coords, 1, 5, 6, 1),
+		3: testWay(3, coords, 3, 7, 8, 9, 3),
+	}
+	mp, problems := AssembleArea(multipolygon(
+		RelationMember{ID: 1, Type: WayType, Role: "outer"},
+		RelationMember{ID: 2, Type: WayType, Role: "inner"},
+		RelationMember{ID: 3, Type: WayType, Role: "outer"},
+	), ways)
+	assert.Len(t, problems, 0)
+	assert.Len(t, mp, 2)
+	assert.Len(t, mp[0].Inners, 1)
+	assert.Len(t, mp[1].Inners, 0)
+}
+
+func TestRingProbe(t *testing.T) {
+	for _, r := range []Ring{
+		// square, counterclockwise and clockwise
+		{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}},
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		// U shape, where the midpoint of most segments is not inside
+		{{0, 0}, {0, 3}, {0, 6}, {0, 9}, {9, 9}, {9, 6}, {3, 6}, {3, 3}, {9, 3}, {9, 0}, {0, 0}},
+		// triangle with a single lowest vertex
+		{{0, 5}, {10, 10}, {10, 0}, {0, 5}},
+	} {
+		p := ringProbe(r)
+		assert.True(t, pointInRing(p, r), "%v", r)
+		for i := 1; i < len(r); i++ {
+			assert.NotEqual(t, 0.0, orientation(r[i-1], r[i], p), "%v on segment %d", p, i)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package gosmparse
+
+// AreaRules decide whether a closed way describes an area (polygon) or a line
+// string, based on its tags. The tag area=yes always makes a closed way an
+// area, area=no never does. Otherwise, a closed way is an area if any of its
+// tags matches a rule. A tag value of "no" never matches.
+type AreaRules struct {
+	keys map[string]*areaKeyRule
+}
+
+type areaKeyRule struct {
+	// anyValue means that all values except the ones in values match. If it
+	// is false, only the ones in values match.
+	anyValue bool
+	values   map[string]bool
+}
+
+// NewAreaRules returns an empty rule set, in which only the area tag is
+// considered.
+func NewAreaRules() *AreaRules {
+	return &AreaRules{keys: make(map[string]*areaKeyRule)}
+}
+
+// DefaultAreaRules returns a rule set that matches the lists commonly used by
+// osm2pgsql and osmium, e.g. building=*, landuse=*, amenity=*, but neither
+// natural=coastline nor any highway without area=yes.
+func DefaultAreaRules() *AreaRules {
+	r := NewAreaRules()
+	r.AddKey("aeroway", "taxiway", "runway", "stopway", "parking_position", "holding_position")
+	r.AddKey("amenity")
+	r.AddKey("area:highway")
+	r.AddKey("building")
+	r.AddKey("building:part")
+	r.AddKey("craft")
+	r.AddKey("historic")
+	r.AddKey("landuse")
+	r.AddKey("leisure", "track", "slipway")
+	r.AddKey("man_made", "embankment", "pipeline", "cutline", "dyke", "groyne", "breakwater")
+	r.AddKey("military")
+	r.AddKey("natural", "coastline", "cliff", "ridge", "arete", "tree_row", "valley", "earth_bank")
+	r.AddKey("office")
+	r.AddKey("place")
+	r.AddKey("public_transport")
+	r.AddKey("ruins")
+	r.AddKey("shop")
+	r.AddKey("tourism")
+	r.AddKey("water")
+	r.AddKey("wetland")
+	r.AddValues("highway", "services", "rest_area")
+	r.AddValues("power", "plant", "substation", "generator", "transformer")
+	r.AddValues("railway", "station", "platform", "turntable")
+	r.AddValues("waterway", "riverbank", "dock", "boatyard", "dam")
+	return r
+}
+
+// AddKey adds a rule that makes every value of key indicate an area, except
+// for the given values. It replaces any previous rule for key.
+func (r *AreaRules) AddKey(key string, except ...string) {
+	r.keys[key] = &areaKeyRule{anyValue: true, values: valueSet(except)}
+}
+
+// AddValues adds a rule that makes the given values of key indicate an area.
+// It replaces any previous rule for key.
+func (r *AreaRules) AddValues(key string, values ...string) {
+	r.keys[key] = &areaKeyRule{values: valueSet(values)}
+}
+
+func valueSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// IsArea reports whether w is closed and its tags describe an area.
+func (r *AreaRules) IsArea(w Way) bool {
+	return IsClosed(w) && r.HasAreaTags(w.Tags)
+}
+
+// HasAreaTags reports whether tags describe an area, regardless of the
+// geometry.
+func (r *AreaRules) HasAreaTags(tags map[string]string) bool {
+	switch tags["area"] {
+	case "yes":
+		return true
+	case "no":
+		return false
+	}
+	for k, v := range tags {
+		rule, ok := r.keys[k]
+		if !ok || v == "no" {
+			continue
+		}
+		if rule.anyValue != rule.values[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClosed reports whether the first and the last node of w are the same. A
+// closed way needs at least four node references, so it can form a ring.
+func IsClosed(w Way) bool {
+	return len(w.NodeIDs) >= 4 && w.NodeIDs[0] == w.NodeIDs[len(w.NodeIDs)-1]
+}
@@ -0,0 +1,54 @@
+package gosmparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultAreaRules(t *testing.T) {
+	closed := []int64{1, 2, 3, 1}
+	cases := []struct {
+		Tags  map[string]string
+		Nodes []int64
+		Area  bool
+	}{
+		{map[string]string{"building": "yes"}, closed, true},
+		{map[string]string{"building": "house"}, closed, true},
+		{map[string]string{"building": "no"}, closed, false},
+		{map[string]string{"building": "yes"}, []int64{1, 2, 3, 4}, false},
+		{map[string]string{"building": "yes"}, []int64{1, 2, 1}, false},
+		{map[string]string{"highway": "residential"}, closed, false},
+		{map[string]string{"highway": "pedestrian"}, closed, false},
+		{map[string]string{"highway": "pedestrian", "area": "yes"}, closed, true},
+		{map[string]string{"highway": "services"}, closed, true},
+		{map[string]string{"landuse": "forest", "area": "no"}, closed, false},
+		{map[string]string{"natural": "coastline"}, closed, false},
+		{map[string]string{"natural": "wood"}, closed, true},
+		{map[string]string{"power": "line"}, closed, false},
+		{map[string]string{"power": "substation"}, closed, true},
+		{map[string]string{"barrier": "fence", "name": "foo"}, closed, false},
+		{map[string]string{"barrier": "fence", "leisure": "park"}, closed, true},
+		{map[string]string{}, closed, false},
+	}
+
+	rules := DefaultAreaRules()
+	for _, c := range cases {
+		w := Way{Element: Element{Tags: c.Tags}, NodeIDs: c.Nodes}
+		assert.Equal(t, c.Area, rules.IsArea(w), "%v %v", c.Tags, c.Nodes)
+	}
+}
+
+func TestCustomAreaRules(t *testing.T) {
+	rules := NewAreaRules()
+	assert.False(t, rules.HasAreaTags(map[string]string{"building": "yes"}))
+	assert.True(t, rules.HasAreaTags(map[string]string{"area": "yes"}))
+
+	rules.AddValues("highway", "pedestrian")
+	assert.True(t, rules.HasAreaTags(map[string]string{"highway": "pedestrian"}))
+	assert.False(t, rules.HasAreaTags(map[string]string{"highway": "primary"}))
+
+	rules.AddKey("highway", "primary")
+	assert.True(t, rules.HasAreaTags(map[string]string{"highway": "secondary"}))
+	assert.False(t, rules.HasAreaTags(map[string]string{"highway": "primary"}))
+}
@@ -0,0 +1,128 @@
+package gosmparse
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Action describes what an OsmChange file does with an element.
+type Action int
+
+const (
+	ActionCreate Action = iota
+	ActionModify
+	ActionDelete
+)
+
+// String returns the name of the action as used in OsmChange files.
+func (a Action) String() string {
+	switch a {
+	case ActionCreate:
+		return "create"
+	case ActionModify:
+		return "modify"
+	case ActionDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ChangeReader is the interface that needs to be implemented in order to receive
+// elements from OsmChange files.
+type ChangeReader interface {
+	OSMReader
+	// ReadAction is called whenever an action block starts. All elements that
+	// are read afterwards belong to this action, until ReadAction is called again.
+	ReadAction(Action)
+}
+
+// A ChangeDecoder reads and decodes OsmChange (.osc) files. Gzip compressed
+// input (.osc.gz) is detected and decompressed automatically.
+type ChangeDecoder struct {
+	r io.Reader
+}
+
+// NewChangeDecoder returns a new decoder that reads from r. As change files are
+// not useful without meta data, the Info field of the elements is always
+// populated. Elements inside a delete block have Info.Visible set to false.
+func NewChangeDecoder(r io.Reader) *ChangeDecoder {
+	return &ChangeDecoder{r: r}
+}
+
+// Parse starts the parsing process that will stream data into the given
+// ChangeReader. Elements are delivered sequentially in file order.
+func (d *ChangeDecoder) Parse(o ChangeReader) error {
+	r, err := maybeGzip(d.r)
+	if err != nil {
+		return err
+	}
+	cr := &changeAdapter{ChangeReader: o}
+	return parseXML(xml.NewDecoder(r), cr, true, func(se xml.StartElement) error {
+		switch se.Name.Local {
+		case "create":
+			cr.action = ActionCreate
+		case "modify":
+			cr.action = ActionModify
+		case "delete":
+			cr.action = ActionDelete
+		case "node", "way", "relation":
+			if !cr.inBlock {
+				return fmt.Errorf("%s outside of create/modify/delete block", se.Name.Local)
+			}
+			return nil
+		default:
+			return nil
+		}
+		cr.inBlock = true
+		o.ReadAction(cr.action)
+		return nil
+	}, func(ee xml.EndElement) {
+		switch ee.Name.Local {
+		case "create", "modify", "delete":
+			cr.inBlock = false
+		}
+	})
+}
+
+// maybeGzip returns a decompressing reader if r starts with the gzip magic
+// number and a buffered version of r otherwise.
+func maybeGzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// changeAdapter keeps track of the current action and marks deleted elements
+// as invisible.
+type changeAdapter struct {
+	ChangeReader
+	inBlock bool
+	action  Action
+}
+
+func (c *changeAdapter) ReadNode(n Node) {
+	c.mark(n.Info)
+	c.ChangeReader.ReadNode(n)
+}
+
+func (c *changeAdapter) ReadWay(w Way) {
+	c.mark(w.Info)
+	c.ChangeReader.ReadWay(w)
+}
+
+func (c *changeAdapter) ReadRelation(r Relation) {
+	c.mark(r.Info)
+	c.ChangeReader.ReadRelation(r)
+}
+
+func (c *changeAdapter) mark(i *Info) {
+	if c.action == ActionDelete {
+		i.Visible = false
+	}
+}
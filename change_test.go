@@ -0,0 +1,87 @@
+package gosmparse
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type changeRecord struct {
+	Action Action
+	Type   MemberType
+	ID     int64
+	Info   *Info
+}
+
+type recordingChangeReader struct {
+	action  Action
+	records []changeRecord
+}
+
+func (r *recordingChangeReader) ReadAction(a Action) {
+	r.action = a
+}
+
+func (r *recordingChangeReader) ReadNode(n Node) {
+	r.records = append(r.records, changeRecord{r.action, NodeType, n.ID, n.Info})
+}
+
+func (r *recordingChangeReader) ReadWay(w Way) {
+	r.records = append(r.records, changeRecord{r.action, WayType, w.ID, w.Info})
+}
+
+func (r *recordingChangeReader) ReadRelation(rel Relation) {
+	r.records = append(r.records, changeRecord{r.action, RelationType, rel.ID, rel.Info})
+}
+
+func TestChangeDecoder(t *testing.T) {
+	for _, fn := range []string{"testdata/change.osc", "testdata/change.osc.gz"} {
+		t.Run(fn, func(t *testing.T) {
+			f, err := os.Open(fn)
+			assert.Nil(t, err)
+			defer f.Close()
+
+			rec := &recordingChangeReader{}
+			assert.Nil(t, NewChangeDecoder(f).Parse(rec))
+			assert.Len(t, rec.records, 5)
+
+			expected := []struct {
+				Action  Action
+				Type    MemberType
+				ID      int64
+				Version int
+				Visible bool
+			}{
+				{ActionCreate, NodeType, 3, 1, true},
+				{ActionModify, NodeType, 1, 2, true},
+				{ActionModify, WayType, 1, 2, true},
+				{ActionDelete, RelationType, 1, 2, false},
+				{ActionDelete, NodeType, 2, 2, false},
+			}
+			for i, exp := range expected {
+				r := rec.records[i]
+				assert.Equal(t, exp.Action, r.Action)
+				assert.Equal(t, exp.Type, r.Type)
+				assert.Equal(t, exp.ID, r.ID)
+				assert.Equal(t, exp.Version, r.Info.Version)
+				assert.Equal(t, exp.Visible, r.Info.Visible)
+			}
+		})
+	}
+}
+
+func TestChangeDecoderOutsideBlock(t *testing.T) {
+	for _, doc := range []string{
+		`<osmChange><node id="1" lat="0" lon="0"/></osmChange>`,
+		`<osmChange><create><node id="1" lat="0" lon="0"/></create><node id="2" lat="0" lon="0"/></osmChange>`,
+		`<osmChange><delete></delete><way id="1"/></osmChange>`,
+	} {
+		err := NewChangeDecoder(strings.NewReader(doc)).Parse(&recordingChangeReader{})
+		assert.NotNil(t, err, doc)
+		if err != nil {
+			assert.Contains(t, err.Error(), "outside of create/modify/delete block")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+)
+
+// A ChangeEncoder writes elements as OsmChange (.osc) to an output stream.
+// Every element belongs to the action that has been set last with
+// WriteAction; consecutive elements with the same action are grouped into one
+// block. Deleted elements are written with their ID and meta data only, marked
+// as not visible.
+type ChangeEncoder struct {
+	// Generator is written into the generator attribute of the osmChange
+	// element.
+	Generator string
+
+	xml     *XMLEncoder
+	action  Action
+	inBlock bool
+	block   Action
+}
+
+// NewChangeEncoder returns a new encoder that writes to w. Generator needs to
+// be set before the first element is written. The initial action is
+// ActionCreate.
+func NewChangeEncoder(w io.Writer) *ChangeEncoder {
+	x := NewXMLEncoder(w)
+	x.root = "osmChange"
+	x.indent = "    "
+	return &ChangeEncoder{Generator: x.Generator, xml: x}
+}
+
+// WriteAction sets the action of the following elements.
+func (e *ChangeEncoder) WriteAction(a Action) error {
+	if a < ActionCreate || a > ActionDelete {
+		return fmt.Errorf("invalid action %v", a)
+	}
+	e.action = a
+	return nil
+}
+
+// WriteNode writes a node element.
+func (e *ChangeEncoder) WriteNode(n Node) error {
+	if err := e.prepare(); err != nil {
+		return err
+	}
+	if e.action == ActionDelete {
+		return e.xml.writeDeleted("node", &n.Element)
+	}
+	return e.xml.WriteNode(n)
+}
+
+// WriteWay writes a way element.
+func (e *ChangeEncoder) WriteWay(w Way) error {
+	if err := e.prepare(); err != nil {
+		return err
+	}
+	if e.action == ActionDelete {
+		return e.xml.writeDeleted("way", &w.Element)
+	}
+	return e.xml.WriteWay(w)
+}
+
+// WriteRelation writes a relation element.
+func (e *ChangeEncoder) WriteRelation(r Relation) error {
+	if err := e.prepare(); err != nil {
+		return err
+	}
+	if e.action == ActionDelete {
+		return e.xml.writeDeleted("relation", &r.Element)
+	}
+	return e.xml.WriteRelation(r)
+}
+
+// Close finishes the document and flushes all buffered data. It does not close
+// the underlying writer.
+func (e *ChangeEncoder) Close() error {
+	if !e.xml.closed {
+		if err := e.start(); err != nil {
+			return err
+		}
+		e.closeBlock()
+	}
+	return e.xml.Close()
+}
+
+func (e *ChangeEncoder) start() error {
+	e.xml.Generator = e.Generator
+	return e.xml.start()
+}
+
+// prepare starts a new action block if the action has changed.
+func (e *ChangeEncoder) prepare() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if e.inBlock && e.block == e.action {
+		return nil
+	}
+	e.closeBlock()
+	e.xml.printf("  <%s>\n", e.action)
+	e.inBlock, e.block = true, e.action
+	return e.xml.err
+}
+
+func (e *ChangeEncoder) closeBlock() {
+	if e.inBlock {
+		e.xml.printf("  </%s>\n", e.block)
+		e.inBlock = false
+	}
+}
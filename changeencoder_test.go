@@ -0,0 +1,91 @@
+package gosmparse
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// changeCopier writes all elements of a change file to enc.
+type changeCopier struct {
+	t   *testing.T
+	enc *ChangeEncoder
+}
+
+func (c *changeCopier) ReadAction(a Action) {
+	assert.Nil(c.t, c.enc.WriteAction(a))
+}
+
+func (c *changeCopier) ReadNode(n Node) {
+	assert.Nil(c.t, c.enc.WriteNode(n))
+}
+
+func (c *changeCopier) ReadWay(w Way) {
+	assert.Nil(c.t, c.enc.WriteWay(w))
+}
+
+func (c *changeCopier) ReadRelation(r Relation) {
+	assert.Nil(c.t, c.enc.WriteRelation(r))
+}
+
+func TestChangeEncoderRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/change.osc")
+	assert.Nil(t, err)
+	defer f.Close()
+	orig := &recordingChangeReader{}
+	assert.Nil(t, NewChangeDecoder(f).Parse(orig))
+
+	_, err = f.Seek(0, 0)
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	enc := NewChangeEncoder(&buf)
+	enc.Generator = "test"
+	assert.Nil(t, NewChangeDecoder(f).Parse(&changeCopier{t: t, enc: enc}))
+	assert.Nil(t, enc.Close())
+	assert.True(t, strings.HasPrefix(buf.String(),
+		`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<osmChange version="0.6" generator="test">`+"\n"+"  <create>\n    <node "))
+	// consecutive elements with the same action share a block
+	assert.Equal(t, 1, strings.Count(buf.String(), "<modify>"))
+
+	written := &recordingChangeReader{}
+	assert.Nil(t, NewChangeDecoder(&buf).Parse(written))
+	assert.Equal(t, len(orig.records), len(written.records))
+	for i := range orig.records {
+		o, w := orig.records[i], written.records[i]
+		assert.Equal(t, o.Action, w.Action)
+		assert.Equal(t, o.Type, w.Type)
+		assert.Equal(t, o.ID, w.ID)
+		assert.Equal(t, o.Info.Version, w.Info.Version)
+		assert.True(t, o.Info.Timestamp.Equal(w.Info.Timestamp))
+	}
+}
+
+func TestChangeEncoderEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewChangeEncoder(&buf)
+	assert.NotNil(t, enc.WriteAction(Action(7)))
+	assert.Nil(t, enc.Close())
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<osmChange version="0.6" generator="gosmparse">`+"\n</osmChange>\n", buf.String())
+	assert.NotNil(t, enc.WriteNode(Node{}))
+}
+
+func TestChangeEncoderDelete(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewChangeEncoder(&buf)
+	assert.Nil(t, enc.WriteAction(ActionDelete))
+	assert.Nil(t, enc.WriteNode(Node{
+		Element: Element{ID: 1, Tags: map[string]string{"amenity": "bench"}, Info: &Info{Version: 2, Visible: true}},
+		Lat:     1, Lon: 2,
+	}))
+	assert.Nil(t, enc.WriteWay(Way{Element: Element{ID: 2}, NodeIDs: []int64{1, 2}}))
+	assert.Nil(t, enc.WriteRelation(Relation{Element: Element{ID: 3}, Members: []RelationMember{{ID: 1}}}))
+	assert.Nil(t, enc.Close())
+	assert.Contains(t, buf.String(), "  <delete>\n"+
+		`    <node id="1" version="2" changeset="0" uid="0" user="" visible="false"/>`+"\n"+
+		`    <way id="2"/>`+"\n"+
+		`    <relation id="3"/>`+"\n"+
+		"  </delete>\n")
+}
@@ -0,0 +1,159 @@
+package gosmparse
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/thomersch/gosmparse/OSMPBF"
+)
+
+// A Changeset groups the changes a user has uploaded together. PBF files only
+// contain the ID of changesets; the other fields are populated by
+// ChangesetDecoder.
+type Changeset struct {
+	ID        int64
+	CreatedAt time.Time
+	// ClosedAt is zero for open changesets.
+	ClosedAt time.Time
+	Open     bool
+	UID      int
+	User     string
+	// NumChanges is the number of elements that have been changed.
+	NumChanges int
+	// BoundingBox is nil for changesets without changes.
+	BoundingBox *BoundingBox
+	Tags        map[string]string
+	// CommentsCount is the number of discussion comments, which may be larger
+	// than len(Comments) if the dump does not contain the discussions.
+	CommentsCount int
+	Comments      []ChangesetComment
+}
+
+// ChangesetComment is a comment of the discussion of a changeset.
+type ChangesetComment struct {
+	Date time.Time
+	UID  int
+	User string
+	Text string
+}
+
+// ChangesetReader receives changesets. It can optionally be implemented by an
+// OSMReader that is passed to Decoder; otherwise changesets in PBF files are
+// skipped.
+type ChangesetReader interface {
+	ReadChangeset(Changeset)
+}
+
+func changesets(o OSMReader, cs []*OSMPBF.ChangeSet) {
+	cr, ok := o.(ChangesetReader)
+	if !ok {
+		return
+	}
+	for _, c := range cs {
+		cr.ReadChangeset(Changeset{ID: c.GetId()})
+	}
+}
+
+// A ChangesetDecoder reads and decodes the changeset XML dumps of the OSM
+// planet (changesets-*.osm) and the changeset API. Compressed input (.gz,
+// .bz2) is detected and decompressed automatically.
+type ChangesetDecoder struct {
+	r io.Reader
+}
+
+// NewChangesetDecoder returns a new decoder that reads from r.
+func NewChangesetDecoder(r io.Reader) *ChangesetDecoder {
+	return &ChangesetDecoder{r: r}
+}
+
+// Parse starts the parsing process that will stream the changesets into the
+// given ChangesetReader. They are delivered sequentially in file order.
+func (d *ChangesetDecoder) Parse(o ChangesetReader) error {
+	r, err := maybeGzip(d.r)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+	r = br
+	if magic, err := br.Peek(3); err == nil && string(magic) == "BZh" {
+		r = bzip2.NewReader(br)
+	}
+
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "changeset" {
+			continue
+		}
+		var c xmlChangeset
+		if err := dec.DecodeElement(&c, &se); err != nil {
+			return err
+		}
+		o.ReadChangeset(c.changeset())
+	}
+}
+
+// xmlChangeset is the XML representation of a changeset.
+type xmlChangeset struct {
+	ID            int64     `xml:"id,attr"`
+	CreatedAt     time.Time `xml:"created_at,attr"`
+	ClosedAt      time.Time `xml:"closed_at,attr"`
+	Open          bool      `xml:"open,attr"`
+	UID           int       `xml:"uid,attr"`
+	User          string    `xml:"user,attr"`
+	MinLat        *float64  `xml:"min_lat,attr"`
+	MinLon        *float64  `xml:"min_lon,attr"`
+	MaxLat        *float64  `xml:"max_lat,attr"`
+	MaxLon        *float64  `xml:"max_lon,attr"`
+	NumChanges    int       `xml:"num_changes,attr"`
+	ChangesCount  int       `xml:"changes_count,attr"`
+	CommentsCount int       `xml:"comments_count,attr"`
+
+	Tags     []xmlTag              `xml:"tag"`
+	Comments []xmlChangesetComment `xml:"discussion>comment"`
+}
+
+type xmlChangesetComment struct {
+	Date time.Time `xml:"date,attr"`
+	UID  int       `xml:"uid,attr"`
+	User string    `xml:"user,attr"`
+	Text string    `xml:"text"`
+}
+
+func (c *xmlChangeset) changeset() Changeset {
+	cs := Changeset{
+		ID:            c.ID,
+		CreatedAt:     c.CreatedAt,
+		ClosedAt:      c.ClosedAt,
+		Open:          c.Open,
+		UID:           c.UID,
+		User:          c.User,
+		NumChanges:    c.NumChanges,
+		Tags:          make(map[string]string, len(c.Tags)),
+		CommentsCount: c.CommentsCount,
+	}
+	// The planet dump and the API use different names.
+	if cs.NumChanges == 0 {
+		cs.NumChanges = c.ChangesCount
+	}
+	if c.MinLat != nil && c.MinLon != nil && c.MaxLat != nil && c.MaxLon != nil {
+		cs.BoundingBox = &BoundingBox{MinLat: *c.MinLat, MinLon: *c.MinLon, MaxLat: *c.MaxLat, MaxLon: *c.MaxLon}
+	}
+	for _, t := range c.Tags {
+		cs.Tags[t.Key] = t.Value
+	}
+	for _, cm := range c.Comments {
+		cs.Comments = append(cs.Comments, ChangesetComment{Date: cm.Date, UID: cm.UID, User: cm.User, Text: cm.Text})
+	}
+	return cs
+}
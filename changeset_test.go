@@ -0,0 +1,102 @@
+package gosmparse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thomersch/gosmparse/OSMPBF"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+type changesetCollector struct {
+	mtx        sync.Mutex
+	Changesets []Changeset
+}
+
+func (c *changesetCollector) ReadChangeset(cs Changeset) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.Changesets = append(c.Changesets, cs)
+}
+
+type changesetCachedReader struct {
+	cachedReader
+	changesetCollector
+}
+
+func TestChangesetDecoder(t *testing.T) {
+	f, err := os.Open("testdata/changesets.osm")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	c := &changesetCollector{}
+	assert.Nil(t, NewChangesetDecoder(f).Parse(c))
+	assert.Len(t, c.Changesets, 2)
+
+	cs := c.Changesets[0]
+	assert.Equal(t, int64(1), cs.ID)
+	assert.True(t, time.Date(2015, 11, 1, 19, 0, 0, 0, time.UTC).Equal(cs.CreatedAt))
+	assert.True(t, time.Date(2015, 11, 1, 20, 0, 0, 0, time.UTC).Equal(cs.ClosedAt))
+	assert.False(t, cs.Open)
+	assert.Equal(t, 1, cs.UID)
+	assert.Equal(t, "Dummy User", cs.User)
+	assert.Equal(t, 5, cs.NumChanges)
+	assert.Equal(t, &BoundingBox{MinLat: 0.001, MinLon: 0.001, MaxLat: 0.002, MaxLon: 0.002}, cs.BoundingBox)
+	assert.Equal(t, map[string]string{"comment": "Add a line", "created_by": "JOSM/1.5 (8969 en)"}, cs.Tags)
+	assert.Equal(t, 2, cs.CommentsCount)
+	assert.Equal(t, []ChangesetComment{
+		{Date: time.Date(2015, 11, 2, 8, 0, 0, 0, time.UTC), UID: 2, User: "Another User", Text: "Welcome!"},
+		{Date: time.Date(2015, 11, 2, 9, 30, 0, 0, time.UTC), UID: 1, User: "Dummy User", Text: "Thanks & see you"},
+	}, cs.Comments)
+
+	cs = c.Changesets[1]
+	assert.Equal(t, int64(2), cs.ID)
+	assert.True(t, cs.Open)
+	assert.True(t, cs.ClosedAt.IsZero())
+	assert.Nil(t, cs.BoundingBox)
+	assert.Empty(t, cs.Tags)
+	assert.Empty(t, cs.Comments)
+}
+
+func TestChangesetDecoderGzip(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/changesets.osm")
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write(raw)
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+
+	c := &changesetCollector{}
+	assert.Nil(t, NewChangesetDecoder(&buf).Parse(c))
+	assert.Len(t, c.Changesets, 2)
+}
+
+func TestDecodePBFChangesets(t *testing.T) {
+	pb := &OSMPBF.PrimitiveBlock{
+		Stringtable: &OSMPBF.StringTable{S: []string{""}},
+		Primitivegroup: []*OSMPBF.PrimitiveGroup{
+			{Changesets: []*OSMPBF.ChangeSet{{Id: proto.Int64(3)}, {Id: proto.Int64(5)}}},
+		},
+	}
+	buf, err := proto.Marshal(pb)
+	assert.Nil(t, err)
+	blob := &OSMPBF.Blob{Raw: buf}
+
+	r := &changesetCachedReader{}
+	d := NewDecoder(nil)
+	d.o = r
+	assert.Nil(t, d.readElements(blob))
+	assert.Equal(t, []Changeset{{ID: 3}, {ID: 5}}, r.Changesets)
+
+	// readers without ReadChangeset skip them
+	d.o = &cachedReader{}
+	assert.Nil(t, d.readElements(blob))
+}
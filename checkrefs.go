@@ -0,0 +1,213 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A MissingRef is a reference to an element that does not exist in the file.
+type MissingRef struct {
+	// Type and ID identify the referencing way or relation.
+	Type MemberType
+	ID   int64
+	// RefType and RefID identify the missing element.
+	RefType MemberType
+	RefID   int64
+}
+
+func (m MissingRef) String() string {
+	return fmt.Sprintf("%s %d references missing %s %d", m.Type, m.ID, m.RefType, m.RefID)
+}
+
+// CheckRefsOptions configures CheckRefs.
+type CheckRefsOptions struct {
+	// Relations also checks members of relations that are relations. As they
+	// may be stored after the referencing relation, these references are
+	// kept in memory until the end of the file.
+	Relations bool
+	// Missing is called for every reference to a missing element.
+	Missing func(MissingRef)
+	// Cycle is called for every group of relations that reference each other
+	// in a cycle, with their IDs in ascending order. Setting it enables the
+	// detection, which keeps all references between relations in memory.
+	Cycle func(ids []int64)
+}
+
+// CheckRefs reads the PBF file r once and reports all references of ways to
+// missing nodes and of relations to missing nodes and ways (and relations, if
+// enabled). The file needs to be sorted by type. The IDs are stored in IDSets,
+// which need about 2 bytes per ID in sparse ranges and at most 8 KiB per range
+// of 65536 IDs, so dense data costs about one bit per ID.
+func CheckRefs(r io.Reader, opts CheckRefsOptions) error {
+	c := &refChecker{
+		opts:  opts,
+		nodes: NewIDSet(),
+		ways:  NewIDSet(),
+		rels:  NewIDSet(),
+	}
+	dec := NewDecoder(r)
+	// A single worker keeps the file order, which is needed to check
+	// references within a single pass.
+	dec.Workers = 1
+	if err := dec.Parse(c); err != nil {
+		return err
+	}
+	if c.err != nil {
+		return c.err
+	}
+
+	if opts.Relations {
+		for _, ref := range c.relRefs {
+			if !c.rels.Has(ref.RefID) {
+				c.missing(ref)
+			}
+		}
+	}
+	if opts.Cycle != nil {
+		for _, cycle := range findCycles(c.relRefs, c.rels) {
+			opts.Cycle(cycle)
+		}
+	}
+	return nil
+}
+
+// refChecker stores the IDs of all elements and checks the references against
+// the elements that have been read before.
+type refChecker struct {
+	opts              CheckRefsOptions
+	nodes, ways, rels *IDSet
+	lastType          MemberType
+	// relRefs are the references between relations.
+	relRefs []MissingRef
+	err     error
+}
+
+func (c *refChecker) checkOrder(t MemberType, id int64) bool {
+	if c.err != nil {
+		return false
+	}
+	if t < c.lastType {
+		c.err = fmt.Errorf("file is not sorted by type: %s %d after %ss", t, id, c.lastType)
+		return false
+	}
+	c.lastType = t
+	return true
+}
+
+func (c *refChecker) missing(ref MissingRef) {
+	if c.opts.Missing != nil {
+		c.opts.Missing(ref)
+	}
+}
+
+func (c *refChecker) ReadNode(n Node) {
+	if c.checkOrder(NodeType, n.ID) {
+		c.nodes.Add(n.ID)
+	}
+}
+
+func (c *refChecker) ReadWay(w Way) {
+	if !c.checkOrder(WayType, w.ID) {
+		return
+	}
+	c.ways.Add(w.ID)
+	for _, id := range w.NodeIDs {
+		if !c.nodes.Has(id) {
+			c.missing(MissingRef{Type: WayType, ID: w.ID, RefType: NodeType, RefID: id})
+		}
+	}
+}
+
+func (c *refChecker) ReadRelation(r Relation) {
+	if !c.checkOrder(RelationType, r.ID) {
+		return
+	}
+	c.rels.Add(r.ID)
+	for _, m := range r.Members {
+		ref := MissingRef{Type: RelationType, ID: r.ID, RefType: m.Type, RefID: m.ID}
+		switch m.Type {
+		case NodeType:
+			if !c.nodes.Has(m.ID) {
+				c.missing(ref)
+			}
+		case WayType:
+			if !c.ways.Has(m.ID) {
+				c.missing(ref)
+			}
+		case RelationType:
+			if c.opts.Relations || c.opts.Cycle != nil {
+				c.relRefs = append(c.relRefs, ref)
+			}
+		}
+	}
+}
+
+// findCycles returns the strongly connected components of the graph of
+// existing relations that contain a cycle, using Tarjan's algorithm.
+func findCycles(refs []MissingRef, exists *IDSet) [][]int64 {
+	edges := make(map[int64][]int64)
+	for _, ref := range refs {
+		if exists.Has(ref.RefID) {
+			edges[ref.ID] = append(edges[ref.ID], ref.RefID)
+		}
+	}
+	ids := make([]int64, 0, len(edges))
+	for id := range edges {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var (
+		index   = make(map[int64]int)
+		lowlink = make(map[int64]int)
+		onStack = make(map[int64]bool)
+		stack   []int64
+		cycles  [][]int64
+		connect func(id int64)
+	)
+	connect = func(id int64) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		selfLoop := false
+		for _, ref := range edges[id] {
+			if ref == id {
+				selfLoop = true
+			}
+			if _, visited := index[ref]; !visited {
+				connect(ref)
+				if lowlink[ref] < lowlink[id] {
+					lowlink[id] = lowlink[ref]
+				}
+			} else if onStack[ref] && index[ref] < lowlink[id] {
+				lowlink[id] = index[ref]
+			}
+		}
+		if lowlink[id] != index[id] {
+			return
+		}
+		var scc []int64
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == id {
+				break
+			}
+		}
+		if len(scc) > 1 || selfLoop {
+			sort.Slice(scc, func(i, j int) bool { return scc[i] < scc[j] })
+			cycles = append(cycles, scc)
+		}
+	}
+	for _, id := range ids {
+		if _, visited := index[id]; !visited {
+			connect(id)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
@@ -0,0 +1,54 @@
+package gosmparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRefs(t *testing.T) {
+	file := func() *cachedReader {
+		return &cachedReader{
+			Nodes: []Node{{Element: Element{ID: -5}}, {Element: Element{ID: 1}}, {Element: Element{ID: 70000}}},
+			Ways: []Way{
+				{Element: Element{ID: 1}, NodeIDs: []int64{1, 2, 70000, -5, -6}},
+			},
+			Rels: []Relation{
+				{Element: Element{ID: 1}, Members: []RelationMember{
+					{ID: 1, Type: NodeType}, {ID: 1, Type: WayType}, {ID: 2, Type: WayType},
+					{ID: 2, Type: RelationType}, {ID: 9, Type: RelationType},
+				}},
+				{Element: Element{ID: 2}, Members: []RelationMember{{ID: 3, Type: RelationType}}},
+				{Element: Element{ID: 3}, Members: []RelationMember{{ID: 1, Type: RelationType}}},
+				{Element: Element{ID: 4}, Members: []RelationMember{{ID: 4, Type: RelationType}, {ID: 1, Type: RelationType}}},
+			},
+		}
+	}
+
+	var missing []string
+	opts := CheckRefsOptions{Missing: func(m MissingRef) {
+		missing = append(missing, m.String())
+	}}
+	assert.Nil(t, CheckRefs(encodePBF(t, Header{}, file()), opts))
+	assert.Equal(t, []string{
+		"way 1 references missing node 2",
+		"way 1 references missing node -6",
+		"relation 1 references missing way 2",
+	}, missing)
+
+	missing = nil
+	var cycles [][]int64
+	opts.Relations = true
+	opts.Cycle = func(ids []int64) {
+		cycles = append(cycles, ids)
+	}
+	assert.Nil(t, CheckRefs(encodePBF(t, Header{}, file()), opts))
+	assert.Equal(t, "relation 1 references missing relation 9", missing[len(missing)-1])
+	assert.Equal(t, [][]int64{{1, 2, 3}, {4}}, cycles)
+}
+
+func TestCheckRefsUnsorted(t *testing.T) {
+	err := CheckRefs(unsortedTestFile(t), CheckRefsOptions{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not sorted by type")
+}
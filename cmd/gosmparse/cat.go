@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runCat(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output file (default stdout)")
+	outFormat := fs.String("f", "", "output format: pbf, osm, o5m or opl (default detected from -o)")
+	inFormat := fs.String("F", "", "input format: pbf, osm, osc, o5m or opl (default detected from file names)")
+	typeList := fs.String("t", "", "comma separated list of element types to copy: node, way, relation (default all)")
+	if err := parseArgs(fs, args, 1, -1); err != nil {
+		return err
+	}
+	types, err := parseTypes(*typeList)
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(*output, *outFormat, stdout)
+	if err != nil {
+		return err
+	}
+	c := &copier{out: out, types: types, keepHeader: fs.NArg() == 1}
+	for _, path := range fs.Args() {
+		if err := c.copyFile(path, *inFormat); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	return out.Close()
+}
+
+// parseTypes parses a comma separated list of element types. An empty list
+// selects all types.
+func parseTypes(list string) ([3]bool, error) {
+	var types [3]bool
+	if list == "" {
+		return [3]bool{true, true, true}, nil
+	}
+	for _, name := range strings.Split(list, ",") {
+		switch strings.TrimSpace(name) {
+		case "n", "node", "nodes":
+			types[gosmparse.NodeType] = true
+		case "w", "way", "ways":
+			types[gosmparse.WayType] = true
+		case "r", "relation", "relations":
+			types[gosmparse.RelationType] = true
+		default:
+			return types, fmt.Errorf("unknown element type %q", name)
+		}
+	}
+	return types, nil
+}
+
+// copier is an OSMReader that writes all elements of the selected types to
+// out. It needs to be called sequentially.
+type copier struct {
+	out        *outputFile
+	types      [3]bool
+	keepHeader bool
+	err        error
+}
+
+func (c *copier) copyFile(path, format string) error {
+	in, err := openInput(path, format)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := in.Parse(c); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return c.err
+}
+
+func (c *copier) ReadHeader(h gosmparse.Header) {
+	if c.keepHeader {
+		c.out.SetHeader(h)
+	}
+}
+
+func (c *copier) ReadNode(n gosmparse.Node) {
+	if c.err == nil && c.types[gosmparse.NodeType] {
+		c.err = c.out.WriteNode(n)
+	}
+}
+
+func (c *copier) ReadWay(w gosmparse.Way) {
+	if c.err == nil && c.types[gosmparse.WayType] {
+		c.err = c.out.WriteWay(w)
+	}
+}
+
+func (c *copier) ReadRelation(r gosmparse.Relation) {
+	if c.err == nil && c.types[gosmparse.RelationType] {
+		c.err = c.out.WriteRelation(r)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatTypeFilter(t *testing.T) {
+	out := runCommand(t, runCat, "-f", "opl", "-t", "way", "../../testdata/way_kv.osm.pbf")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 3)
+	for _, l := range lines {
+		assert.True(t, strings.HasPrefix(l, "w"), l)
+		assert.Contains(t, l, " dV ")
+	}
+}
+
+func TestCatConcatenate(t *testing.T) {
+	out := runCommand(t, runCat, "-f", "opl", "-t", "n,r",
+		"../../testdata/node_kv.osm.pbf", "../../testdata/change.osc.gz")
+	single := runCommand(t, runCat, "-f", "opl", "-t", "n,r", "../../testdata/node_kv.osm.pbf")
+	assert.True(t, strings.HasPrefix(out, single))
+	assert.True(t, len(out) > len(single))
+}
+
+func TestCatConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	direct := runCommand(t, runCat, "-f", "opl", "../../testdata/history.osh")
+	for _, ext := range []string{".osm.pbf", ".osh", ".o5m", ".opl.gz"} {
+		path := filepath.Join(dir, "out"+ext)
+		runCommand(t, runCat, "-o", path, "../../testdata/history.osh")
+		assert.Equal(t, direct, runCommand(t, runCat, "-f", "opl", path), ext)
+	}
+}
+
+func TestCatErrors(t *testing.T) {
+	_, err := createOutput("", "", nil)
+	assert.NotNil(t, err)
+	_, err = createOutput("out.osc", "", nil)
+	assert.NotNil(t, err)
+	_, _, err = detectFormat("file.txt")
+	assert.NotNil(t, err)
+	_, err = parseTypes("node,area")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runCheckRefs(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	relations := fs.Bool("r", false, "also check members of relations that are relations")
+	cycles := fs.Bool("c", false, "report cycles of relations")
+	quiet := fs.Bool("q", false, "only print the number of problems")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var nMissing, nCycles int
+	opts := gosmparse.CheckRefsOptions{
+		Relations: *relations,
+		Missing: func(m gosmparse.MissingRef) {
+			nMissing++
+			if !*quiet {
+				fmt.Fprintln(stdout, m)
+			}
+		},
+	}
+	if *cycles {
+		opts.Cycle = func(ids []int64) {
+			nCycles++
+			if !*quiet {
+				fmt.Fprintf(stdout, "cycle of relations %v\n", ids)
+			}
+		}
+	}
+	if err := gosmparse.CheckRefs(f, opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%d missing references", nMissing)
+	if *cycles {
+		fmt.Fprintf(stdout, ", %d cycles", nCycles)
+	}
+	fmt.Fprintln(stdout)
+	if nMissing > 0 || nCycles > 0 {
+		return errProblemsFound
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRefs(t *testing.T) {
+	out := runCommand(t, runCheckRefs, "-r", "-c", "../../testdata/way_kv.osm.pbf")
+	assert.Equal(t, "0 missing references, 0 cycles\n", out)
+
+	var buf bytes.Buffer
+	err := runCheckRefs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"../../testdata/relation.pbf"}, &buf)
+	assert.Equal(t, errProblemsFound, err)
+	assert.Contains(t, buf.String(), "relation 1 references missing node 15\n")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runDiff(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output file (default stdout)")
+	format := fs.String("f", "summary", "output format: summary, detailed or osc")
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	var d differ
+	switch *format {
+	case "summary":
+		d = &summaryDiffer{}
+	case "detailed":
+		d = &detailedDiffer{}
+	case "osc":
+		d = &oscDiffer{}
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+
+	a, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	b, err := os.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	w := stdout
+	var f *os.File
+	if *output != "" && *output != "-" {
+		if f, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	d.start(bw)
+	if err := gosmparse.Diff(a, b, d.entry); err != nil {
+		return err
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if f != nil {
+		return f.Close()
+	}
+	return nil
+}
+
+// differ writes the entries of a diff in one of the output formats.
+type differ interface {
+	start(w io.Writer)
+	entry(e gosmparse.DiffEntry) error
+	finish() error
+}
+
+type summaryDiffer struct {
+	w      io.Writer
+	counts [3][4]int
+}
+
+func (d *summaryDiffer) start(w io.Writer) {
+	d.w = w
+}
+
+func (d *summaryDiffer) entry(e gosmparse.DiffEntry) error {
+	d.counts[e.Type][e.Kind]++
+	return nil
+}
+
+func (d *summaryDiffer) finish() error {
+	fmt.Fprintf(d.w, "%-10s %10s %10s %10s %10s\n", "", "only in a", "only in b", "changed", "unchanged")
+	for t, name := range []string{"nodes", "ways", "relations"} {
+		c := d.counts[t]
+		_, err := fmt.Fprintf(d.w, "%-10s %10d %10d %10d %10d\n", name,
+			c[gosmparse.DiffOnlyA], c[gosmparse.DiffOnlyB], c[gosmparse.DiffChanged], c[gosmparse.DiffUnchanged])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type detailedDiffer struct {
+	w io.Writer
+}
+
+func (d *detailedDiffer) start(w io.Writer) {
+	d.w = w
+}
+
+func (d *detailedDiffer) entry(e gosmparse.DiffEntry) error {
+	var err error
+	switch e.Kind {
+	case gosmparse.DiffOnlyA:
+		_, err = fmt.Fprintf(d.w, "- %s %d%s\n", e.Type, e.ID, version(e.Old))
+	case gosmparse.DiffOnlyB:
+		_, err = fmt.Fprintf(d.w, "+ %s %d%s\n", e.Type, e.ID, version(e.New))
+	case gosmparse.DiffChanged:
+		_, err = fmt.Fprintf(d.w, "* %s %d%s ->%s (%s)\n", e.Type, e.ID, version(e.Old), version(e.New), e.Changes)
+	}
+	return err
+}
+
+func (d *detailedDiffer) finish() error {
+	return nil
+}
+
+// version returns " vN" for elements with meta data.
+func version(v interface{}) string {
+	var info *gosmparse.Info
+	switch e := v.(type) {
+	case gosmparse.Node:
+		info = e.Info
+	case gosmparse.Way:
+		info = e.Info
+	case gosmparse.Relation:
+		info = e.Info
+	}
+	if info == nil {
+		return ""
+	}
+	return fmt.Sprintf(" v%d", info.Version)
+}
+
+// oscDiffer writes a change file that transforms a into b. Created and
+// modified elements are written as they come, ordered nodes, ways, relations.
+// Deletions are written at the end in reverse order (relations, ways, nodes),
+// so that no element is deleted while it is still referenced. Only the ID and
+// meta data of deleted elements are kept until then.
+type oscDiffer struct {
+	enc     *gosmparse.ChangeEncoder
+	deletes [3][]gosmparse.Element
+}
+
+func (d *oscDiffer) start(w io.Writer) {
+	d.enc = gosmparse.NewChangeEncoder(w)
+}
+
+func (d *oscDiffer) entry(e gosmparse.DiffEntry) error {
+	switch e.Kind {
+	case gosmparse.DiffOnlyA:
+		d.deletes[e.Type] = append(d.deletes[e.Type], deletedElement(e.Old))
+		return nil
+	case gosmparse.DiffOnlyB:
+		return d.write(gosmparse.ActionCreate, e.New)
+	case gosmparse.DiffChanged:
+		return d.write(gosmparse.ActionModify, e.New)
+	}
+	return nil
+}
+
+// deletedElement returns the ID and meta data of the element el.
+func deletedElement(el interface{}) gosmparse.Element {
+	var e gosmparse.Element
+	switch v := el.(type) {
+	case gosmparse.Node:
+		e = v.Element
+	case gosmparse.Way:
+		e = v.Element
+	case gosmparse.Relation:
+		e = v.Element
+	}
+	return gosmparse.Element{ID: e.ID, Info: e.Info}
+}
+
+func (d *oscDiffer) write(a gosmparse.Action, el interface{}) error {
+	if err := d.enc.WriteAction(a); err != nil {
+		return err
+	}
+	switch v := el.(type) {
+	case gosmparse.Node:
+		return d.enc.WriteNode(v)
+	case gosmparse.Way:
+		return d.enc.WriteWay(v)
+	default:
+		return d.enc.WriteRelation(v.(gosmparse.Relation))
+	}
+}
+
+func (d *oscDiffer) finish() error {
+	if err := d.enc.WriteAction(gosmparse.ActionDelete); err != nil {
+		return err
+	}
+	for t := len(d.deletes) - 1; t >= 0; t-- {
+		for _, e := range d.deletes[t] {
+			var err error
+			switch gosmparse.MemberType(t) {
+			case gosmparse.NodeType:
+				err = d.enc.WriteNode(gosmparse.Node{Element: e})
+			case gosmparse.WayType:
+				err = d.enc.WriteWay(gosmparse.Way{Element: e})
+			default:
+				err = d.enc.WriteRelation(gosmparse.Relation{Element: e})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return d.enc.Close()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomersch/gosmparse"
+)
+
+func TestDiff(t *testing.T) {
+	a, b := "../../testdata/way_kv.osm.pbf", "../../testdata/relation_kv.osm.pbf"
+	out := runCommand(t, runDiff, a, b)
+	assert.Contains(t, out, "nodes               2          0          0          2\n")
+	assert.Contains(t, out, "relations           0          2          0          0\n")
+
+	out = runCommand(t, runDiff, "-f", "detailed", a, b)
+	assert.Equal(t, []string{
+		"- node 3 v1", "- node 4 v1", "* way 1 v1 -> v1 (tags)", "- way 2 v1", "- way 3 v1",
+		"+ relation 1 v1", "+ relation 2 v1",
+	}, strings.Split(strings.TrimSpace(out), "\n"))
+}
+
+func TestDiffOSC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	a, b := "../../testdata/way_kv.osm.pbf", "../../testdata/relation_kv.osm.pbf"
+	osc := filepath.Join(dir, "a-b.osc")
+	runCommand(t, runDiff, "-f", "osc", "-o", osc, a, b)
+
+	// creations and modifications come first, deletions last in reverse
+	// order of types
+	f, err := os.Open(osc)
+	assert.Nil(t, err)
+	defer f.Close()
+	order := &changeOrder{}
+	assert.Nil(t, gosmparse.NewChangeDecoder(f).Parse(order))
+	assert.Equal(t, []string{
+		"modify way 1", "create relation 1", "create relation 2",
+		"delete way 2", "delete way 3", "delete node 3", "delete node 4",
+	}, order.entries)
+	written, err := ioutil.ReadFile(osc)
+	assert.Nil(t, err)
+	deletes := string(written[bytes.Index(written, []byte("<delete>")):])
+	assert.NotContains(t, deletes, "<tag")
+	assert.NotContains(t, deletes, "<nd")
+	assert.NotContains(t, deletes, `visible="true"`)
+
+	// applying the change file to a results in b
+	base, err := os.Open(a)
+	assert.Nil(t, err)
+	defer base.Close()
+	change, err := os.Open(osc)
+	assert.Nil(t, err)
+	defer change.Close()
+	var applied bytes.Buffer
+	assert.Nil(t, gosmparse.ApplyChanges(&applied, base, []io.Reader{change}, gosmparse.ApplyOptions{}))
+
+	expected, err := os.Open(b)
+	assert.Nil(t, err)
+	defer expected.Close()
+	err = gosmparse.Diff(&applied, expected, func(e gosmparse.DiffEntry) error {
+		assert.Equal(t, gosmparse.DiffUnchanged, e.Kind, "%s %d", e.Type, e.ID)
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+// changeOrder records the action, type and ID of all elements of a change
+// file.
+type changeOrder struct {
+	action  gosmparse.Action
+	entries []string
+}
+
+func (c *changeOrder) ReadAction(a gosmparse.Action) { c.action = a }
+
+func (c *changeOrder) ReadNode(n gosmparse.Node) { c.add(gosmparse.NodeType, n.ID) }
+
+func (c *changeOrder) ReadWay(w gosmparse.Way) { c.add(gosmparse.WayType, w.ID) }
+
+func (c *changeOrder) ReadRelation(r gosmparse.Relation) { c.add(gosmparse.RelationType, r.ID) }
+
+func (c *changeOrder) add(t gosmparse.MemberType, id int64) {
+	c.entries = append(c.entries, fmt.Sprintf("%s %s %d", c.action, t, id))
+}
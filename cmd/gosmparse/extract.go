@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runExtract(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output file (default stdout)")
+	outFormat := fs.String("f", "", "output format: pbf, osm, o5m or opl (default detected from -o, pbf for stdout)")
+	bbox := fs.String("b", "", "bounding box as MINLON,MINLAT,MAXLON,MAXLAT")
+	polygon := fs.String("p", "", "polygon file in .poly or GeoJSON format")
+	strategy := fs.String("s", "complete_ways", "strategy: simple, complete_ways or smart")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if (*bbox == "") == (*polygon == "") {
+		return fmt.Errorf("either -b or -p needs to be set")
+	}
+
+	var (
+		region gosmparse.Region
+		err    error
+	)
+	if *bbox != "" {
+		region, err = parseBBox(*bbox)
+	} else {
+		region, err = readRegion(*polygon)
+	}
+	if err != nil {
+		return err
+	}
+	st, err := parseStrategy(*strategy)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if *outFormat == "" && (*output == "" || *output == "-") {
+		*outFormat = formatPBF
+	}
+	out, err := createOutput(*output, *outFormat, stdout)
+	if err != nil {
+		return err
+	}
+	dec := gosmparse.NewExtractDecoder(f, region)
+	dec.Strategy = st
+	dec.WithInfo = true
+	dec.Workers = 1
+	c := &copier{out: out, types: [3]bool{true, true, true}, keepHeader: true}
+	if err := dec.Parse(c); err != nil {
+		out.Close()
+		return err
+	}
+	if c.err != nil {
+		out.Close()
+		return c.err
+	}
+	return out.Close()
+}
+
+func parseBBox(s string) (gosmparse.BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return gosmparse.BoundingBox{}, fmt.Errorf("invalid bounding box %q", s)
+	}
+	var c [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return gosmparse.BoundingBox{}, fmt.Errorf("invalid bounding box %q", s)
+		}
+		c[i] = v
+	}
+	b := gosmparse.BoundingBox{MinLon: c[0], MinLat: c[1], MaxLon: c[2], MaxLat: c[3]}
+	if b.MinLon > b.MaxLon || b.MinLat > b.MaxLat {
+		return b, fmt.Errorf("invalid bounding box %q: minimum greater than maximum", s)
+	}
+	return b, nil
+}
+
+func readRegion(path string) (gosmparse.MultiPolygon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var mp gosmparse.MultiPolygon
+	if strings.HasSuffix(strings.ToLower(path), ".poly") {
+		mp, err = gosmparse.ReadPoly(f)
+	} else {
+		mp, err = gosmparse.ReadGeoJSONRegion(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return mp, nil
+}
+
+func parseStrategy(s string) (gosmparse.ExtractStrategy, error) {
+	for _, st := range []gosmparse.ExtractStrategy{
+		gosmparse.ExtractSimple, gosmparse.ExtractCompleteWays, gosmparse.ExtractSmart,
+	} {
+		if st.String() == s {
+			return st, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown strategy %q", s)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomersch/gosmparse"
+)
+
+func TestExtract(t *testing.T) {
+	// way_kv contains node 1 at 0.001 and nodes 2-4 at 0.002
+	out := runCommand(t, runExtract, "-b", "0,0,0.0015,0.0015", "-s", "simple", "-f", "opl", "../../testdata/way_kv.osm.pbf")
+	nodes, others := countLines(out)
+	assert.Equal(t, 1, nodes)
+	assert.Equal(t, 1, others)
+
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	poly := filepath.Join(dir, "area.poly")
+	assert.Nil(t, ioutil.WriteFile(poly, []byte("area\n1\n0 0\n0.0015 0\n0.0015 0.0015\n0 0.0015\nEND\nEND\n"), 0644))
+	pbf := filepath.Join(dir, "out.osm.pbf")
+	runCommand(t, runExtract, "-p", poly, "-o", pbf, "../../testdata/way_kv.osm.pbf")
+
+	fi, err := readFileInfo(pbf)
+	assert.Nil(t, err)
+	assert.Equal(t, &gosmparse.BoundingBox{MaxLat: 0.0015, MaxLon: 0.0015}, fi.Header.BoundingBox)
+	assert.Equal(t, int64(2), fi.Data.Nodes.Count)
+	assert.Equal(t, int64(1), fi.Data.Ways.Count)
+}
+
+func TestParseBBox(t *testing.T) {
+	b, err := parseBBox("13.1,52.3, 13.7,52.7")
+	assert.Nil(t, err)
+	assert.Equal(t, gosmparse.BoundingBox{MinLon: 13.1, MinLat: 52.3, MaxLon: 13.7, MaxLat: 52.7}, b)
+	for _, s := range []string{"1,2,3", "a,b,c,d", "2,2,1,1"} {
+		_, err := parseBBox(s)
+		assert.NotNil(t, err, s)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thomersch/gosmparse"
+	"github.com/thomersch/gosmparse/OSMPBF"
+)
+
+type fileInfo struct {
+	File struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	} `json:"file"`
+	Header struct {
+		BoundingBox               *gosmparse.BoundingBox `json:"bbox,omitempty"`
+		RequiredFeatures          []string               `json:"required_features"`
+		OptionalFeatures          []string               `json:"optional_features"`
+		WritingProgram            string                 `json:"writing_program,omitempty"`
+		Source                    string                 `json:"source,omitempty"`
+		ReplicationTimestamp      *time.Time             `json:"replication_timestamp,omitempty"`
+		ReplicationSequenceNumber int64                  `json:"replication_sequence_number,omitempty"`
+		ReplicationBaseURL        string                 `json:"replication_base_url,omitempty"`
+	} `json:"header"`
+	Blobs struct {
+		Count       int            `json:"count"`
+		Compression map[string]int `json:"compression"`
+	} `json:"blobs"`
+	Data struct {
+		Nodes     typeStats  `json:"nodes"`
+		Ways      typeStats  `json:"ways"`
+		Relations typeStats  `json:"relations"`
+		FirstTime *time.Time `json:"first_timestamp,omitempty"`
+		LastTime  *time.Time `json:"last_timestamp,omitempty"`
+		Sorted    bool       `json:"sorted"`
+	} `json:"data"`
+}
+
+type typeStats struct {
+	Count int64 `json:"count"`
+	MinID int64 `json:"min_id"`
+	MaxID int64 `json:"max_id"`
+}
+
+func (s *typeStats) add(id int64) {
+	if s.Count == 0 || id < s.MinID {
+		s.MinID = id
+	}
+	if s.Count == 0 || id > s.MaxID {
+		s.MaxID = id
+	}
+	s.Count++
+}
+
+func runFileinfo(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	asJSON := fs.Bool("j", false, "write JSON instead of text")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	fi, err := readFileInfo(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fi)
+	}
+	return fi.writeText(stdout)
+}
+
+func readFileInfo(path string) (*fileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi := &fileInfo{}
+	fi.File.Name = path
+	if st, err := f.Stat(); err == nil {
+		fi.File.Size = st.Size()
+	}
+	if err := fi.scanBlobs(f); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	dec := gosmparse.NewDecoderWithInfo(f)
+	// A single worker keeps the file order, which is needed for the sort check.
+	dec.Workers = 1
+	c := &infoCollector{fi: fi, sorted: true}
+	if err := dec.Parse(c); err != nil {
+		return nil, err
+	}
+	fi.Data.Sorted = c.sorted
+	return fi, nil
+}
+
+// scanBlobs counts the blobs of the file and their compression types.
+func (fi *fileInfo) scanBlobs(r io.Reader) error {
+	fi.Blobs.Compression = make(map[string]int)
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		buf := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		bh := &OSMPBF.BlobHeader{}
+		if err := bh.UnmarshalVT(buf); err != nil {
+			return err
+		}
+		buf = make([]byte, bh.GetDatasize())
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		b := &OSMPBF.Blob{}
+		if err := b.UnmarshalVT(buf); err != nil {
+			return err
+		}
+		fi.Blobs.Count++
+		fi.Blobs.Compression[compression(b)]++
+	}
+}
+
+func compression(b *OSMPBF.Blob) string {
+	switch {
+	case b.Raw != nil:
+		return "none"
+	case b.ZlibData != nil:
+		return "zlib"
+	case b.LzmaData != nil:
+		return "lzma"
+	case b.OBSOLETEBzip2Data != nil:
+		return "bzip2"
+	}
+	return "unknown"
+}
+
+// infoCollector gathers the statistics of the elements. It relies on being
+// called sequentially in file order.
+type infoCollector struct {
+	fi *fileInfo
+
+	sorted   bool
+	lastType gosmparse.MemberType
+	lastID   int64
+	lastVer  int
+	started  bool
+}
+
+func (c *infoCollector) ReadHeader(h gosmparse.Header) {
+	hd := &c.fi.Header
+	hd.BoundingBox = h.BoundingBox
+	hd.RequiredFeatures = append([]string{}, h.RequiredFeatures...)
+	hd.OptionalFeatures = append([]string{}, h.OptionalFeatures...)
+	hd.WritingProgram = h.WritingProgram
+	hd.Source = h.Source
+	if !h.ReplicationTimestamp.IsZero() {
+		ts := h.ReplicationTimestamp.UTC()
+		hd.ReplicationTimestamp = &ts
+	}
+	hd.ReplicationSequenceNumber = h.ReplicationSequenceNumber
+	hd.ReplicationBaseURL = h.ReplicationBaseURL
+}
+
+func (c *infoCollector) ReadNode(n gosmparse.Node) {
+	c.fi.Data.Nodes.add(n.ID)
+	c.element(gosmparse.NodeType, n.Element)
+}
+
+func (c *infoCollector) ReadWay(w gosmparse.Way) {
+	c.fi.Data.Ways.add(w.ID)
+	c.element(gosmparse.WayType, w.Element)
+}
+
+func (c *infoCollector) ReadRelation(r gosmparse.Relation) {
+	c.fi.Data.Relations.add(r.ID)
+	c.element(gosmparse.RelationType, r.Element)
+}
+
+func (c *infoCollector) element(t gosmparse.MemberType, e gosmparse.Element) {
+	var version int
+	if e.Info != nil {
+		version = e.Info.Version
+		if ts := e.Info.Timestamp; !ts.IsZero() {
+			ts = ts.UTC()
+			d := &c.fi.Data
+			if d.FirstTime == nil || ts.Before(*d.FirstTime) {
+				d.FirstTime = &ts
+			}
+			if d.LastTime == nil || ts.After(*d.LastTime) {
+				d.LastTime = &ts
+			}
+		}
+	}
+
+	// Sorted means ordered by type, then ID and, for history files, version.
+	if c.started {
+		switch {
+		case t < c.lastType:
+			c.sorted = false
+		case t > c.lastType:
+		case e.ID < c.lastID:
+			c.sorted = false
+		case e.ID == c.lastID && version <= c.lastVer:
+			c.sorted = false
+		}
+	}
+	c.started = true
+	c.lastType, c.lastID, c.lastVer = t, e.ID, version
+}
+
+func (fi *fileInfo) writeText(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("File:\n")
+	fmt.Fprintf(&b, "  Name: %s\n", fi.File.Name)
+	fmt.Fprintf(&b, "  Size: %d\n", fi.File.Size)
+
+	h := fi.Header
+	b.WriteString("Header:\n")
+	if bb := h.BoundingBox; bb != nil {
+		fmt.Fprintf(&b, "  Bounding box: (%g,%g,%g,%g)\n", bb.MinLon, bb.MinLat, bb.MaxLon, bb.MaxLat)
+	}
+	fmt.Fprintf(&b, "  Required features: %s\n", strings.Join(h.RequiredFeatures, " "))
+	fmt.Fprintf(&b, "  Optional features: %s\n", strings.Join(h.OptionalFeatures, " "))
+	fmt.Fprintf(&b, "  Writing program: %s\n", h.WritingProgram)
+	if h.Source != "" {
+		fmt.Fprintf(&b, "  Source: %s\n", h.Source)
+	}
+	if h.ReplicationTimestamp != nil {
+		fmt.Fprintf(&b, "  Replication timestamp: %s\n", h.ReplicationTimestamp.Format(time.RFC3339))
+	}
+	if h.ReplicationSequenceNumber != 0 {
+		fmt.Fprintf(&b, "  Replication sequence number: %d\n", h.ReplicationSequenceNumber)
+	}
+	if h.ReplicationBaseURL != "" {
+		fmt.Fprintf(&b, "  Replication base URL: %s\n", h.ReplicationBaseURL)
+	}
+
+	b.WriteString("Blobs:\n")
+	fmt.Fprintf(&b, "  Count: %d\n", fi.Blobs.Count)
+	var comp []string
+	for c, n := range fi.Blobs.Compression {
+		comp = append(comp, fmt.Sprintf("%s (%d)", c, n))
+	}
+	sort.Strings(comp)
+	fmt.Fprintf(&b, "  Compression: %s\n", strings.Join(comp, ", "))
+
+	d := fi.Data
+	b.WriteString("Data:\n")
+	for _, t := range []struct {
+		name  string
+		stats typeStats
+	}{{"Nodes", d.Nodes}, {"Ways", d.Ways}, {"Relations", d.Relations}} {
+		fmt.Fprintf(&b, "  %s: %d", t.name, t.stats.Count)
+		if t.stats.Count > 0 {
+			fmt.Fprintf(&b, " (IDs %d to %d)", t.stats.MinID, t.stats.MaxID)
+		}
+		b.WriteString("\n")
+	}
+	if d.FirstTime != nil {
+		fmt.Fprintf(&b, "  First timestamp: %s\n", d.FirstTime.Format(time.RFC3339))
+		fmt.Fprintf(&b, "  Last timestamp: %s\n", d.LastTime.Format(time.RFC3339))
+	}
+	sorted := "no"
+	if d.Sorted {
+		sorted = "yes"
+	}
+	fmt.Fprintf(&b, "  Sorted: %s\n", sorted)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runCommand(t *testing.T, run func(*flag.FlagSet, []string, io.Writer) error, args ...string) string {
+	var buf bytes.Buffer
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	assert.Nil(t, run(fs, args, &buf))
+	return buf.String()
+}
+
+func TestFileinfo(t *testing.T) {
+	out := runCommand(t, runFileinfo, "../../testdata/history.osh.pbf")
+	assert.Contains(t, out, "Required features: OsmSchema-V0.6 DenseNodes HistoricalInformation\n")
+	assert.Contains(t, out, "Compression: zlib (4)\n")
+	assert.Contains(t, out, "Nodes: 4 (IDs 1 to 2)\n")
+	assert.Contains(t, out, "Relations: 2 (IDs 1 to 2)\n")
+	assert.Contains(t, out, "First timestamp: 2015-11-01T19:00:00Z\n")
+	assert.Contains(t, out, "Last timestamp: 2019-04-01T19:00:00Z\n")
+	assert.Contains(t, out, "Sorted: no\n")
+}
+
+func TestFileinfoJSON(t *testing.T) {
+	out := runCommand(t, runFileinfo, "-j", "../../testdata/way_kv.osm.pbf")
+	var fi fileInfo
+	assert.Nil(t, json.Unmarshal([]byte(out), &fi))
+	assert.Equal(t, 2, fi.Blobs.Count)
+	assert.Equal(t, "0.44.1", fi.Header.WritingProgram)
+	assert.Equal(t, typeStats{Count: 4, MinID: 1, MaxID: 4}, fi.Data.Nodes)
+	assert.Equal(t, typeStats{Count: 3, MinID: 1, MaxID: 3}, fi.Data.Ways)
+	assert.Equal(t, int64(0), fi.Data.Relations.Count)
+	assert.True(t, fi.Data.Sorted)
+}
+
+func TestFileinfoUsage(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	assert.Equal(t, errUsage, runFileinfo(fs, nil, &bytes.Buffer{}))
+}
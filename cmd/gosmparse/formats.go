@@ -0,0 +1,225 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thomersch/gosmparse"
+)
+
+// Supported file formats.
+const (
+	formatPBF = "pbf"
+	formatXML = "osm"
+	formatOSC = "osc"
+	formatO5M = "o5m"
+	formatOPL = "opl"
+)
+
+// parser is implemented by all decoders of the library.
+type parser interface {
+	Parse(gosmparse.OSMReader) error
+}
+
+// detectFormat returns the format of path by its extension. A trailing .gz is
+// ignored; gz reports whether it was present.
+func detectFormat(path string) (format string, gz bool, err error) {
+	name := strings.ToLower(filepath.Base(path))
+	if strings.HasSuffix(name, ".gz") {
+		name, gz = strings.TrimSuffix(name, ".gz"), true
+	}
+	switch filepath.Ext(name) {
+	case ".pbf":
+		format = formatPBF
+	case ".osm", ".osh", ".xml":
+		format = formatXML
+	case ".osc":
+		format = formatOSC
+	case ".o5m", ".o5c":
+		format = formatO5M
+	case ".opl":
+		format = formatOPL
+	default:
+		return "", false, fmt.Errorf("cannot detect format of %s, use -f", path)
+	}
+	if gz && format == formatPBF {
+		return "", false, fmt.Errorf("%s: gzip compressed PBF is not supported", path)
+	}
+	return format, gz, nil
+}
+
+func checkFormat(format string, output bool) error {
+	switch format {
+	case formatPBF, formatXML, formatO5M, formatOPL:
+		return nil
+	case formatOSC:
+		if !output {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// inputFile is an opened input, which is decoded with metadata. For PBF, a
+// single worker is used, so the elements are delivered in file order.
+type inputFile struct {
+	parser
+	closers []io.Closer
+}
+
+// openInput opens path in the given format. If format is empty, it is
+// detected from the file name.
+func openInput(path, format string) (*inputFile, error) {
+	gz := false
+	if format == "" {
+		var err error
+		if format, gz, err = detectFormat(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkFormat(format, false); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	in := &inputFile{closers: []io.Closer{f}}
+	var r io.Reader = f
+	if gz {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		in.closers = append(in.closers, zr)
+		r = zr
+	}
+
+	switch format {
+	case formatPBF:
+		dec := gosmparse.NewDecoderWithInfo(r)
+		dec.Workers = 1
+		in.parser = dec
+	case formatXML:
+		in.parser = gosmparse.NewXMLDecoderWithInfo(r)
+	case formatOSC:
+		in.parser = changeParser{gosmparse.NewChangeDecoder(r)}
+	case formatO5M:
+		in.parser = gosmparse.NewO5MDecoderWithInfo(r)
+	case formatOPL:
+		in.parser = gosmparse.NewOPLDecoderWithInfo(r)
+	}
+	return in, nil
+}
+
+// changeParser reads change files like regular files. Deleted elements have
+// Info.Visible set to false.
+type changeParser struct {
+	dec *gosmparse.ChangeDecoder
+}
+
+func (p changeParser) Parse(o gosmparse.OSMReader) error {
+	return p.dec.Parse(ignoreActions{o})
+}
+
+type ignoreActions struct {
+	gosmparse.OSMReader
+}
+
+func (ignoreActions) ReadAction(gosmparse.Action) {}
+
+func (in *inputFile) Close() error {
+	var err error
+	for i := len(in.closers) - 1; i >= 0; i-- {
+		if cerr := in.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// outputFile is an opened output. Close finishes the encoder and closes the
+// underlying file.
+type outputFile struct {
+	gosmparse.OSMWriter
+	closers []io.Closer
+}
+
+// createOutput creates path, or uses stdout if path is empty or "-". If
+// format is empty, it is detected from the file name.
+func createOutput(path, format string, stdout io.Writer) (*outputFile, error) {
+	toStdout := path == "" || path == "-"
+	gz := false
+	if format == "" {
+		if toStdout {
+			return nil, fmt.Errorf("output format needs to be set with -f when writing to stdout")
+		}
+		var err error
+		if format, gz, err = detectFormat(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkFormat(format, true); err != nil {
+		return nil, err
+	}
+
+	out := &outputFile{}
+	w := stdout
+	if !toStdout {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		out.closers = append(out.closers, f)
+		w = f
+	}
+	if gz {
+		zw := gzip.NewWriter(w)
+		out.closers = append(out.closers, zw)
+		w = zw
+	}
+
+	switch format {
+	case formatPBF:
+		out.OSMWriter = gosmparse.NewEncoder(w)
+	case formatXML:
+		out.OSMWriter = gosmparse.NewXMLEncoder(w)
+	case formatO5M:
+		out.OSMWriter = gosmparse.NewO5MEncoder(w)
+	case formatOPL:
+		out.OSMWriter = gosmparse.NewOPLEncoder(w)
+	}
+	return out, nil
+}
+
+// SetHeader passes the information of h to the encoder, as far as the output
+// format supports it. It needs to be called before the first element is
+// written.
+func (out *outputFile) SetHeader(h gosmparse.Header) {
+	switch enc := out.OSMWriter.(type) {
+	case *gosmparse.Encoder:
+		h.WritingProgram = "gosmparse"
+		enc.Header = h
+	case *gosmparse.XMLEncoder:
+		enc.Bounds = h.BoundingBox
+	case *gosmparse.O5MEncoder:
+		enc.Bounds = h.BoundingBox
+		enc.Timestamp = h.ReplicationTimestamp
+	}
+}
+
+func (out *outputFile) Close() error {
+	err := out.OSMWriter.Close()
+	for i := len(out.closers) - 1; i >= 0; i-- {
+		if cerr := out.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
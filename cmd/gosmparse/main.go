@@ -0,0 +1,131 @@
+// Command gosmparse inspects and converts OpenStreetMap data files.
+//
+// Usage:
+//
+//	gosmparse <command> [flags] [arguments]
+//
+// Run "gosmparse <command> -h" for the flags of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	// run defines its flags on fs, parses args with it and executes the
+	// command, writing its regular output to stdout.
+	run func(fs *flag.FlagSet, args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{"cat", "[-o OUTPUT] [-f FORMAT] [-F FORMAT] [-t TYPES] FILE...", "concatenate and convert files", runCat},
+	{"check-refs", "[-r] [-c] [-q] FILE", "check a sorted PBF file for references to missing elements", runCheckRefs},
+	{"diff", "[-f FORMAT] [-o OUTPUT] A B", "show the differences between two sorted PBF files", runDiff},
+	{"extract", "(-b BBOX | -p POLYGON) [-s STRATEGY] [-o OUTPUT] [-f FORMAT] FILE", "extract the data of a region from a PBF file", runExtract},
+	{"fileinfo", "[-j] FILE", "show information about a PBF file", runFileinfo},
+	{"merge", "[-o OUTPUT] [-q] FILE...", "merge sorted PBF files", runMerge},
+	{"sort", "[-o OUTPUT] [-m ELEMENTS] [-T DIR] FILE", "sort a PBF file by type, ID and version", runSort},
+	{"tags-filter", "[-o OUTPUT] [-f FORMAT] [-R] FILE EXPRESSION...", "filter a PBF file by tags", runTagsFilter},
+	{"time-filter", "[-o OUTPUT] FILE TIME", "write the state of a sorted history PBF file at a point in time", runTimeFilter},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.exec(os.Args[2:]))
+		}
+	}
+	if name != "help" && name != "-h" && name != "--help" {
+		fmt.Fprintf(os.Stderr, "gosmparse: unknown command %q\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+func (c command) exec(args []string) int {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gosmparse %s %s\n\n%s.\n", c.name, c.args, c.short)
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	err := c.run(fs, args, os.Stdout)
+	switch {
+	case err == flag.ErrHelp:
+		return 0
+	case err == errUsage:
+		return 2
+	case err == errProblemsFound:
+		return 1
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "gosmparse %s: %v\n", c.name, err)
+		return 1
+	}
+	return 0
+}
+
+// errUsage is returned by commands that have been called with invalid
+// arguments, after the usage has been printed.
+var errUsage = fmt.Errorf("invalid arguments")
+
+// errProblemsFound is returned by commands that check files and found
+// problems, which have already been reported.
+var errProblemsFound = fmt.Errorf("problems found")
+
+// parseArgs parses the flags in args and checks that the number of remaining
+// arguments is between min and max. A negative max allows any number. Flags
+// may also follow the positional arguments; all arguments after "--" are
+// positional.
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) error {
+	// The flag package stops at the first positional argument, so parsing is
+	// continued after every positional argument.
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return err
+			}
+			return errUsage
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// Parse consumes the "--" that ends the flags; the remaining arguments
+		// must not be parsed again.
+		if parsed := args[:len(args)-len(rest)]; len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	// Parsing only the positional arguments makes them available as fs.Args.
+	if err := fs.Parse(append([]string{"--"}, positional...)); err != nil {
+		return err
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gosmparse <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.short)
+	}
+}
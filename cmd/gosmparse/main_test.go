@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args       []string
+		output     string
+		positional []string
+	}{
+		{[]string{"-o", "out", "a", "b"}, "out", []string{"a", "b"}},
+		{[]string{"a", "-o", "out", "b"}, "out", []string{"a", "b"}},
+		{[]string{"a", "b", "-o", "out"}, "out", []string{"a", "b"}},
+		{[]string{"a", "--", "-o", "foo"}, "", []string{"a", "-o", "foo"}},
+		{[]string{"-o", "out", "--", "-a", "--", "b"}, "out", []string{"-a", "--", "b"}},
+		{[]string{"--", "a", "-o", "foo"}, "", []string{"a", "-o", "foo"}},
+		{[]string{"a", "-"}, "", []string{"a", "-"}},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		output := fs.String("o", "", "")
+		assert.Nil(t, parseArgs(fs, tc.args, 0, -1), tc.args)
+		assert.Equal(t, tc.output, *output, tc.args)
+		assert.Equal(t, tc.positional, fs.Args(), tc.args)
+	}
+}
+
+func TestParseArgsInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"a", "-x"},
+		{"a", "b", "c"},
+		{},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		fs.String("o", "", "")
+		assert.Equal(t, errUsage, parseArgs(fs, args, 1, 2), args)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	assert.Equal(t, flag.ErrHelp, parseArgs(fs, []string{"a", "-h"}, 1, 1))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runMerge(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output PBF file (default stdout)")
+	quiet := fs.Bool("q", false, "do not report conflicts")
+	if err := parseArgs(fs, args, 1, -1); err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	inputs := make([]io.Reader, len(paths))
+	for i, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		inputs[i] = f
+	}
+	conflicts := 0
+	opts := gosmparse.MergeOptions{Conflict: func(c gosmparse.MergeConflict) {
+		conflicts++
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "conflict: %s %d version %d differs between %s and %s\n",
+				c.Type, c.ID, c.Version, paths[c.Inputs[0]], paths[c.Inputs[1]])
+		}
+	}}
+
+	err := merge(*output, inputs, opts, stdout)
+	if err == nil && conflicts > 0 && !*quiet {
+		fmt.Fprintf(os.Stderr, "%d conflicts, the elements of the first file have been kept\n", conflicts)
+	}
+	return err
+}
+
+func merge(output string, inputs []io.Reader, opts gosmparse.MergeOptions, stdout io.Writer) error {
+	if output == "" || output == "-" {
+		return gosmparse.Merge(stdout, inputs, opts)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := gosmparse.Merge(f, inputs, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
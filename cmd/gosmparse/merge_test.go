@@ -0,0 +1,25 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "merged.osm.pbf")
+	runCommand(t, runMerge, "-q", "-o", out, "../../testdata/way_kv.osm.pbf", "../../testdata/relation_kv.osm.pbf", "../../testdata/way_kv.osm.pbf")
+	fi, err := readFileInfo(out)
+	assert.Nil(t, err)
+	assert.True(t, fi.Data.Sorted)
+	assert.Equal(t, typeStats{Count: 4, MinID: 1, MaxID: 4}, fi.Data.Nodes)
+	assert.Equal(t, typeStats{Count: 3, MinID: 1, MaxID: 3}, fi.Data.Ways)
+	assert.Equal(t, typeStats{Count: 2, MinID: 1, MaxID: 2}, fi.Data.Relations)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runSort(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output PBF file (default stdout)")
+	maxElements := fs.Int("m", 0, "number of elements held in memory before using temporary files (default 4000000)")
+	tempDir := fs.String("T", "", "directory for temporary files")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	opts := gosmparse.SortOptions{MaxElements: *maxElements, TempDir: *tempDir}
+	if *output == "" || *output == "-" {
+		return gosmparse.Sort(stdout, in, opts)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := gosmparse.Sort(f, in, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomersch/gosmparse"
+)
+
+func TestSort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fi, err := readFileInfo("../../testdata/history.osh.pbf")
+	assert.Nil(t, err)
+	assert.False(t, fi.Data.Sorted)
+
+	out := filepath.Join(dir, "sorted.osh.pbf")
+	runCommand(t, runSort, "-m", "3", "-T", dir, "-o", out, "../../testdata/history.osh.pbf")
+	fi, err = readFileInfo(out)
+	assert.Nil(t, err)
+	assert.True(t, fi.Data.Sorted)
+	assert.Contains(t, fi.Header.OptionalFeatures, gosmparse.FeatureSortTypeThenID)
+	assert.Contains(t, fi.Header.RequiredFeatures, gosmparse.FeatureHistorical)
+	assert.Equal(t, int64(4), fi.Data.Nodes.Count)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runTagsFilter(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output file (default stdout)")
+	outFormat := fs.String("f", "", "output format: pbf, osm, o5m or opl (default detected from -o)")
+	omitRefs := fs.Bool("R", false, "omit the nodes of matching ways and the members of matching relations")
+	if err := parseArgs(fs, args, 2, -1); err != nil {
+		return err
+	}
+	filter, err := gosmparse.ParseTagFilter(fs.Args()[1:]...)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	out, err := createOutput(*output, *outFormat, stdout)
+	if err != nil {
+		return err
+	}
+	dec := gosmparse.NewFilterDecoder(f, filter)
+	dec.Complete = !*omitRefs
+	dec.WithInfo = true
+	dec.Workers = 1
+	c := &copier{out: out, types: [3]bool{true, true, true}, keepHeader: true}
+	if err := dec.Parse(c); err != nil {
+		out.Close()
+		return err
+	}
+	if c.err != nil {
+		out.Close()
+		return c.err
+	}
+	return out.Close()
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagsFilter(t *testing.T) {
+	out := runCommand(t, runTagsFilter, "../../testdata/way_kv.osm.pbf", "w/highway=primary", "-f", "opl", "-R")
+	assert.Equal(t, "w1 v1 dV c1 t2015-11-01T19:00:00Z i1 uDummy%20%User Thighway=primary,name=line Nn1,n2\n"+
+		"w2 v1 dV c1 t2015-11-01T19:00:00Z i1 uDummy%20%User Tfoo=bar,highway=primary Nn2,n3\n", out)
+
+	out = runCommand(t, runTagsFilter, "-f", "opl", "../../testdata/way_kv.osm.pbf", "width")
+	nodes, others := countLines(out)
+	assert.Equal(t, 3, nodes)
+	assert.Equal(t, 1, others)
+}
+
+// countLines returns the number of node and other lines of OPL output.
+func countLines(opl string) (nodes, others int) {
+	for _, l := range strings.Split(strings.TrimSpace(opl), "\n") {
+		if strings.HasPrefix(l, "n") {
+			nodes++
+		} else {
+			others++
+		}
+	}
+	return nodes, others
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/thomersch/gosmparse"
+)
+
+func runTimeFilter(fs *flag.FlagSet, args []string, stdout io.Writer) error {
+	output := fs.String("o", "", "output PBF file (default stdout)")
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	t, err := parseTime(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if *output == "" || *output == "-" {
+		return gosmparse.TimeFilter(stdout, in, t)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := gosmparse.TimeFilter(f, in, t); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseTime parses an RFC 3339 timestamp or a date, which is interpreted as
+// midnight UTC.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, expected e.g. 2019-01-01 or 2019-01-01T12:00:00Z", s)
+	}
+	return t, nil
+}
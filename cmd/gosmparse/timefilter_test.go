@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomersch/gosmparse"
+)
+
+func TestTimeFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sorted := filepath.Join(dir, "sorted.osh.pbf")
+	runCommand(t, runSort, "-o", sorted, "../../testdata/history.osh.pbf")
+	out := filepath.Join(dir, "snapshot.pbf")
+	runCommand(t, runTimeFilter, "-o", out, sorted, "2017-01-01")
+
+	fi, err := readFileInfo(out)
+	assert.Nil(t, err)
+	assert.NotContains(t, fi.Header.RequiredFeatures, gosmparse.FeatureHistorical)
+	assert.Equal(t, int64(2), fi.Data.Nodes.Count)
+	assert.Equal(t, int64(1), fi.Data.Ways.Count)
+	assert.Equal(t, int64(1), fi.Data.Relations.Count)
+
+	runCommand(t, runTimeFilter, "-o", out, sorted, "2019-04-01T19:00:00Z")
+	fi, err = readFileInfo(out)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), fi.Data.Nodes.Count)
+	assert.Equal(t, int64(2), fi.Data.Ways.Count)
+}
+
+func TestParseTime(t *testing.T) {
+	tm, err := parseTime("2019-01-01")
+	assert.Nil(t, err)
+	assert.Equal(t, "2019-01-01T00:00:00Z", tm.Format("2006-01-02T15:04:05Z07:00"))
+	_, err = parseTime("yesterday")
+	assert.NotNil(t, err)
+}
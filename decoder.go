@@ -55,7 +55,7 @@ func NewDecoderWithInfo(r io.Reader) *Decoder {
 // Parse starts the parsing process that will stream data into the given OSMReader.
 func (d *Decoder) Parse(o OSMReader) error {
 	d.o = o
-	header, _, err := d.block()
+	header, headerBlob, err := d.block()
 	if err != nil {
 		return err
 	}
@@ -63,54 +63,77 @@ func (d *Decoder) Parse(o OSMReader) error {
 	if header.GetType() != "OSMHeader" {
 		return fmt.Errorf("Invalid header of first data block. Wanted: OSMHeader, have: %s", header.GetType())
 	}
+	if hr, ok := o.(HeaderReader); ok {
+		buf, err := d.blobBytes(headerBlob)
+		if err != nil {
+			return err
+		}
+		hb := &OSMPBF.HeaderBlock{}
+		if err := hb.UnmarshalVT(buf); err != nil {
+			return err
+		}
+		hr.ReadHeader(headerFromPBF(hb))
+	}
+
+	if d.Workers == 0 {
+		d.Workers = runtime.GOMAXPROCS(0)
+	}
+	// The first error stops the feeder and the workers. Parse returns once
+	// all of them have finished, so o is not called afterwards.
+	var (
+		wg       sync.WaitGroup
+		stop     = make(chan struct{})
+		stopOnce sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
 
-	errChan := make(chan error)
 	// feeder
 	blobs := make(chan *OSMPBF.Blob, d.QueueSize)
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		defer close(blobs)
 		for {
 			_, blob, err := d.block()
 			if err != nil {
-				if err == io.EOF {
-					return
+				if err != io.EOF {
+					fail(err)
 				}
-				errChan <- err
 				return
 			}
-			blobs <- blob
+			select {
+			case blobs <- blob:
+			case <-stop:
+				return
+			}
 		}
 	}()
 
-	if d.Workers == 0 {
-		d.Workers = runtime.GOMAXPROCS(0)
-	}
-	var wg sync.WaitGroup
 	for i := 0; i < d.Workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for blob := range blobs {
-				err := d.readElements(blob)
-				if err != nil {
-					errChan <- err
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := d.readElements(blob); err != nil {
+					fail(err)
 					return
 				}
 			}
 		}()
 	}
-
-	finished := make(chan bool)
-	go func() {
-		wg.Wait()
-		finished <- true
-	}()
-	select {
-	case err = <-errChan:
-		return err
-	case <-finished:
-		return nil
-	}
+	wg.Wait()
+	return firstErr
 }
 
 func (d *Decoder) block() (*OSMPBF.BlobHeader, *OSMPBF.Blob, error) {
@@ -162,6 +185,8 @@ func (d *Decoder) readElements(blob *OSMPBF.Blob) error {
 			if err := relation(d.o, pb, pg.Relations, d.infoFn); err != nil {
 				return err
 			}
+		case len(pg.Changesets) != 0:
+			changesets(d.o, pg.Changesets)
 		case len(pg.Nodes) != 0:
 			return fmt.Errorf("Nodes are not supported")
 		default:
@@ -173,6 +198,17 @@ func (d *Decoder) readElements(blob *OSMPBF.Blob) error {
 
 // should be concurrency safe
 func (d *Decoder) blobData(blob *OSMPBF.Blob) (*OSMPBF.PrimitiveBlock, error) {
+	buf, err := d.blobBytes(blob)
+	if err != nil {
+		return nil, err
+	}
+	var primitiveBlock = &OSMPBF.PrimitiveBlock{}
+	err = primitiveBlock.UnmarshalVT(buf)
+	return primitiveBlock, err
+}
+
+// blobBytes returns the uncompressed content of blob.
+func (d *Decoder) blobBytes(blob *OSMPBF.Blob) ([]byte, error) {
 	buf := make([]byte, blob.GetRawSize())
 	switch {
 	case blob.Raw != nil:
@@ -194,7 +230,5 @@ func (d *Decoder) blobData(blob *OSMPBF.Blob) (*OSMPBF.PrimitiveBlock, error) {
 	default:
 		return nil, fmt.Errorf("found block with unknown data")
 	}
-	var primitiveBlock = &OSMPBF.PrimitiveBlock{}
-	err := primitiveBlock.UnmarshalVT(buf)
-	return primitiveBlock, err
+	return buf, nil
 }
@@ -146,6 +146,65 @@ func TestParseHistory(t *testing.T) {
 	assert.Equal(t, or.Rels[1].Info.UID, 2)
 }
 
+func TestParseVisibleDefault(t *testing.T) {
+	testFile, err := os.Open("testdata/relation_kv.osm.pbf")
+	assert.Nil(t, err)
+	defer testFile.Close()
+
+	or := &cachedReader{}
+	err = NewDecoderWithInfo(testFile).Parse(or)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, or.Rels)
+	for _, r := range or.Rels {
+		assert.True(t, r.Info.Visible)
+	}
+}
+
+func TestParseWithoutInfo(t *testing.T) {
+	testFile, err := os.Open("testdata/way_kv.osm.pbf")
+	assert.Nil(t, err)
+	defer testFile.Close()
+
+	or := &cachedReader{}
+	assert.Nil(t, NewDecoder(testFile).Parse(or))
+	assert.NotEmpty(t, or.Nodes)
+	assert.NotEmpty(t, or.Ways)
+	for _, n := range or.Nodes {
+		assert.Nil(t, n.Info)
+	}
+	for _, w := range or.Ways {
+		assert.Nil(t, w.Info)
+	}
+}
+
+func TestParseWithInfoWithoutMetadata(t *testing.T) {
+	buf := encodePBF(t, Header{}, &cachedReader{
+		Nodes: []Node{{Element: Element{ID: 1}}, {Element: Element{ID: 2}}},
+		Ways:  []Way{{Element: Element{ID: 1}, NodeIDs: []int64{1, 2}}},
+	})
+	or := &cachedReader{}
+	assert.Nil(t, NewDecoderWithInfo(buf).Parse(or))
+	assert.Len(t, or.Nodes, 2)
+	assert.Nil(t, or.Nodes[0].Info)
+	assert.Nil(t, or.Ways[0].Info)
+}
+
+func TestParseDateGranularity(t *testing.T) {
+	// The blocks use date granularities of 100 ms, 1 ms and 1.5 s.
+	or := readFile(t, "testdata/date_granularity.pbf", func(f *os.File, o OSMReader) error {
+		dec := NewDecoderWithInfo(f)
+		dec.Workers = 1
+		return dec.Parse(o)
+	})
+	assert.Len(t, or.Nodes, 2)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 500000000, time.UTC), or.Nodes[0].Info.Timestamp.UTC())
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 600000000, time.UTC), or.Nodes[1].Info.Timestamp.UTC())
+	assert.Len(t, or.Ways, 1)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 123000000, time.UTC), or.Ways[0].Info.Timestamp.UTC())
+	assert.Len(t, or.Rels, 1)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 1, 500000000, time.UTC), or.Rels[0].Info.Timestamp.UTC())
+}
+
 func TestBlobDataUncompressed(t *testing.T) {
 	originalPrimBlock := &OSMPBF.PrimitiveBlock{
 		Stringtable: &OSMPBF.StringTable{},
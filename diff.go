@@ -0,0 +1,145 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DiffKind describes how an element differs between two files.
+type DiffKind int
+
+const (
+	// DiffUnchanged is reported for elements that are equal in both files.
+	DiffUnchanged DiffKind = iota
+	// DiffOnlyA is reported for elements that only exist in the first file.
+	DiffOnlyA
+	// DiffOnlyB is reported for elements that only exist in the second file.
+	DiffOnlyB
+	// DiffChanged is reported for elements whose content differs.
+	DiffChanged
+)
+
+// String returns a short description of the kind.
+func (k DiffKind) String() string {
+	switch k {
+	case DiffUnchanged:
+		return "unchanged"
+	case DiffOnlyA:
+		return "only in a"
+	case DiffOnlyB:
+		return "only in b"
+	case DiffChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// DiffChanges is a set of flags that describe which parts of an element have
+// changed.
+type DiffChanges uint
+
+const (
+	ChangedTags DiffChanges = 1 << iota
+	// ChangedLocation is only used for nodes.
+	ChangedLocation
+	// ChangedNodes is only used for ways.
+	ChangedNodes
+	// ChangedMembers is only used for relations.
+	ChangedMembers
+	// ChangedInfo means that any field of Info differs.
+	ChangedInfo
+)
+
+var diffChangeNames = []string{"tags", "location", "nodes", "members", "info"}
+
+// String returns the names of the changed parts, separated by commas.
+func (c DiffChanges) String() string {
+	var names []string
+	for i, name := range diffChangeNames {
+		if c&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// A DiffEntry describes the difference of a single element.
+type DiffEntry struct {
+	Kind    DiffKind
+	Type    MemberType
+	ID      int64
+	Changes DiffChanges
+	// Old is the element of the first file and New the one of the second
+	// file, as Node, Way or Relation. They are nil if the element does not
+	// exist in the respective file.
+	Old, New interface{}
+}
+
+// Diff compares the PBF files a and b and calls fn for every element of both
+// files, ordered by type and ID. Both files need to be sorted by type and ID
+// and must not contain history. They are streamed in parallel, so the memory
+// usage does not depend on their size. If fn returns an error, Diff stops and
+// returns it.
+func Diff(a, b io.Reader, fn func(DiffEntry) error) error {
+	done := make(chan struct{})
+	defer close(done)
+	ra := newPBFStream(a, done).run(0)
+	rb := newPBFStream(b, done).run(1)
+	okA, err := ra.next()
+	if err != nil {
+		return err
+	}
+	okB, err := rb.next()
+	if err != nil {
+		return err
+	}
+
+	for okA || okB {
+		var e DiffEntry
+		advanceA, advanceB := okA, okB
+		switch {
+		case okA && (!okB || ra.head.key().less(rb.head.key())):
+			e = DiffEntry{Kind: DiffOnlyA, Old: ra.head.value()}
+			advanceB = false
+		case okB && (!okA || rb.head.key().less(ra.head.key())):
+			e = DiffEntry{Kind: DiffOnlyB, New: rb.head.value()}
+			advanceA = false
+		default:
+			e = DiffEntry{Kind: DiffUnchanged, Changes: ra.head.changes(&rb.head), Old: ra.head.value(), New: rb.head.value()}
+			if e.Changes != 0 {
+				e.Kind = DiffChanged
+			}
+		}
+		key := ra.head.key()
+		if !advanceA {
+			key = rb.head.key()
+		}
+		e.Type, e.ID = key.Type, key.ID
+		if err := fn(e); err != nil {
+			return err
+		}
+
+		if advanceA {
+			if okA, err = diffNext(ra, key); err != nil {
+				return err
+			}
+		}
+		if advanceB {
+			if okB, err = diffNext(rb, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffNext advances r and makes sure that every element occurs only once.
+func diffNext(r *run, prev entityKey) (bool, error) {
+	ok, err := r.next()
+	if ok && r.head.key() == prev {
+		return false, fmt.Errorf("input %d contains several versions of %s %d, history files are not supported",
+			r.index, prev.Type, prev.ID)
+	}
+	return ok, err
+}
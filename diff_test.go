@@ -0,0 +1,83 @@
+package gosmparse
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	info := func(v int) *Info {
+		return &Info{Version: v, Timestamp: time.Unix(int64(v)*1000, 0), Visible: true}
+	}
+	a := encodePBF(t, Header{}, &cachedReader{
+		Nodes: []Node{
+			{Element: Element{ID: 1, Info: info(1)}, Lat: 1, Lon: 1},
+			{Element: Element{ID: 2, Info: info(1)}, Lat: 2, Lon: 2},
+			{Element: Element{ID: 3, Info: info(1)}, Lat: 3, Lon: 3},
+		},
+		Ways: []Way{
+			{Element: Element{ID: 1, Info: info(1), Tags: map[string]string{"highway": "primary"}}, NodeIDs: []int64{1, 2}},
+		},
+		Rels: []Relation{
+			{Element: Element{ID: 1, Info: info(1)}, Members: []RelationMember{{ID: 1, Type: WayType}}},
+		},
+	})
+	b := encodePBF(t, Header{}, &cachedReader{
+		Nodes: []Node{
+			{Element: Element{ID: 1, Info: info(1)}, Lat: 1, Lon: 1},
+			{Element: Element{ID: 3, Info: info(2)}, Lat: 3, Lon: 3.5},
+			{Element: Element{ID: 4, Info: info(1)}, Lat: 4, Lon: 4},
+		},
+		Ways: []Way{
+			{Element: Element{ID: 1, Info: info(2), Tags: map[string]string{"highway": "secondary"}}, NodeIDs: []int64{1, 3}},
+		},
+	})
+
+	var entries []string
+	err := Diff(a, b, func(e DiffEntry) error {
+		entries = append(entries, fmt.Sprintf("%s %d %s %s", e.Type, e.ID, e.Kind, e.Changes))
+		switch e.Kind {
+		case DiffOnlyA:
+			assert.Nil(t, e.New)
+		case DiffOnlyB:
+			assert.Nil(t, e.Old)
+			assert.IsType(t, Node{}, e.New)
+		default:
+			assert.NotNil(t, e.Old)
+			assert.NotNil(t, e.New)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"node 1 unchanged ",
+		"node 2 only in a ",
+		"node 3 changed location,info",
+		"node 4 only in b ",
+		"way 1 changed tags,nodes,info",
+		"relation 1 only in a ",
+	}, entries)
+}
+
+func TestDiffErrors(t *testing.T) {
+	empty := func() *bytes.Buffer { return encodePBF(t, Header{}, &cachedReader{}) }
+	stop := fmt.Errorf("stop")
+	history := encodePBF(t, Header{}, &cachedReader{Nodes: []Node{
+		{Element: Element{ID: 1, Info: &Info{Version: 1}}},
+		{Element: Element{ID: 1, Info: &Info{Version: 2}}},
+	}})
+	err := Diff(history, empty(), func(DiffEntry) error { return nil })
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "history files are not supported")
+
+	err = Diff(empty(), unsortedTestFile(t), func(DiffEntry) error { return nil })
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "input 1 is not sorted")
+
+	err = Diff(encodePBF(t, Header{}, &cachedReader{Nodes: []Node{{}}}), empty(), func(DiffEntry) error { return stop })
+	assert.Equal(t, stop, err)
+}
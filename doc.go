@@ -3,5 +3,8 @@ Package gosmparse is a library for parsing OpenStreetMap binary PBF files.
 
 It has been designed for very fast, flexible, streamed parsing of small and
 large files.
+
+Besides PBF, OSM XML (.osm and .osh) files can be read with XMLDecoder, which
+streams into the same OSMReader interface.
 */
 package gosmparse
@@ -1,6 +1,7 @@
 package gosmparse
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/thomersch/gosmparse/OSMPBF"
@@ -11,7 +12,8 @@ type Element struct {
 	ID   int64
 	Tags map[string]string
 
-	// Info is only populated if you use NewDecoderWithInfo.
+	// Info is only populated if you use NewDecoderWithInfo (or one of the other
+	// WithInfo constructors).
 	Info *Info
 }
 
@@ -27,6 +29,25 @@ type Node struct {
 type Way struct {
 	Element
 	NodeIDs []int64
+
+	// Coords contains the locations of the nodes in NodeIDs. It is only
+	// populated if you use LocationDecoder; nodes whose location is unknown
+	// are MissingLatLon.
+	Coords []LatLon
+}
+
+// CoordsComplete reports whether Coords contains a known location for every
+// node of the way.
+func (w *Way) CoordsComplete() bool {
+	if len(w.Coords) != len(w.NodeIDs) {
+		return false
+	}
+	for _, ll := range w.Coords {
+		if ll.Missing() {
+			return false
+		}
+	}
+	return true
 }
 
 // Relation is an OSM data element that contains multiple elements (RelationMember)
@@ -46,6 +67,12 @@ type Info struct {
 	Visible   bool
 }
 
+// BoundingBox describes a rectangular area in degrees.
+type BoundingBox struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
 // MemberType describes the type of a relation member (node/way/relation).
 type MemberType int
 
@@ -55,6 +82,20 @@ const (
 	RelationType
 )
 
+// String returns the name of the member type as used in OSM XML ("node", "way"
+// or "relation").
+func (t MemberType) String() string {
+	switch t {
+	case NodeType:
+		return "node"
+	case WayType:
+		return "way"
+	case RelationType:
+		return "relation"
+	}
+	return fmt.Sprintf("MemberType(%d)", int(t))
+}
+
 // RelationMember refers to an element in a relation. It contains the ID of the element
 // (node/way/relation) and the role.
 type RelationMember struct {
@@ -123,7 +164,7 @@ func way(o OSMReader, pb *OSMPBF.PrimitiveBlock, ways []*OSMPBF.Way, infoFn info
 			nodeID = way.Refs[index] + nodeID
 			w.NodeIDs[index] = nodeID
 		}
-		w.Info = info(way.GetInfo(), dateGran, st)
+		w.Info = infoFn(way.GetInfo(), dateGran, st)
 		o.ReadWay(w)
 	}
 	return nil
@@ -167,6 +208,10 @@ func relation(o OSMReader, pb *OSMPBF.PrimitiveBlock, relations []*OSMPBF.Relati
 }
 
 func denseInfo(i *OSMPBF.DenseInfo, ds *denseState, index int) *Info {
+	// Files without meta data have no DenseInfo.
+	if i == nil || len(i.Version) <= index {
+		return nil
+	}
 	ds.OffTime += i.Timestamp[index]
 	ds.OffChangeset += i.Changeset[index]
 	ds.OffUserID += i.Uid[index]
@@ -174,7 +219,7 @@ func denseInfo(i *OSMPBF.DenseInfo, ds *denseState, index int) *Info {
 
 	info := Info{
 		Version:   int(i.Version[index]),
-		Timestamp: time.Unix(ds.OffTime*ds.DateGran/1000, 0),
+		Timestamp: fromDate(ds.OffTime, ds.DateGran),
 		Changeset: ds.OffChangeset,
 		UID:       int(ds.OffUserID),
 		User:      ds.Strings[ds.OffUser],
@@ -193,10 +238,18 @@ func info(i *OSMPBF.Info, gran int64, st []string) *Info {
 	}
 	return &Info{
 		Version:   int(i.GetVersion()),
-		Timestamp: time.Unix(i.GetTimestamp()*gran/1000, 0),
+		Timestamp: fromDate(i.GetTimestamp(), gran),
 		Changeset: i.GetChangeset(),
 		UID:       int(i.GetUid()),
 		User:      st[i.GetUserSid()],
-		Visible:   i.GetVisible(),
+		// Files without history information omit the flag.
+		Visible: i.Visible == nil || i.GetVisible(),
 	}
 }
+
+// fromDate converts a timestamp in units of gran milliseconds, as used by
+// PrimitiveBlock.date_granularity.
+func fromDate(ts, gran int64) time.Time {
+	ms := ts * gran
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
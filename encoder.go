@@ -0,0 +1,402 @@
+package gosmparse
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/thomersch/gosmparse/OSMPBF"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// defaultBlockSize is the number of elements per block, as used by most
+	// other PBF writers.
+	defaultBlockSize = 8000
+	// coordGranularity is the granularity of coordinates in nanodegrees.
+	coordGranularity = 100
+	// maxDateGranularity is the coarsest granularity of timestamps in
+	// milliseconds. Blocks with more precise timestamps use a finer one.
+	maxDateGranularity = 1000
+)
+
+// An Encoder writes elements as OSM PBF to an output stream. Elements are
+// collected into blocks of BlockSize elements of the same type, so the memory
+// usage stays constant.
+type Encoder struct {
+	// Header is written before the first block. It needs to be set before the
+	// first element is written. The required features OsmSchema-V0.6 and
+	// DenseNodes are always added.
+	Header Header
+	// BlockSize is the maximum number of elements per block.
+	BlockSize int
+
+	w       io.Writer
+	started bool
+	closed  bool
+
+	kind  MemberType
+	nodes []Node
+	ways  []Way
+	rels  []Relation
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		Header:    Header{WritingProgram: "gosmparse"},
+		BlockSize: defaultBlockSize,
+		w:         w,
+	}
+}
+
+// WriteNode adds a node to the output.
+func (e *Encoder) WriteNode(n Node) error {
+	if err := e.prepare(NodeType); err != nil {
+		return err
+	}
+	e.nodes = append(e.nodes, n)
+	return e.flushIfFull(len(e.nodes))
+}
+
+// WriteWay adds a way to the output.
+func (e *Encoder) WriteWay(w Way) error {
+	if err := e.prepare(WayType); err != nil {
+		return err
+	}
+	e.ways = append(e.ways, w)
+	return e.flushIfFull(len(e.ways))
+}
+
+// WriteRelation adds a relation to the output.
+func (e *Encoder) WriteRelation(r Relation) error {
+	if err := e.prepare(RelationType); err != nil {
+		return err
+	}
+	e.rels = append(e.rels, r)
+	return e.flushIfFull(len(e.rels))
+}
+
+// Close writes all pending elements. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.closed = true
+	return e.flush()
+}
+
+func (e *Encoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	buf, err := proto.Marshal(e.Header.pbf())
+	if err != nil {
+		return err
+	}
+	return e.writeBlob("OSMHeader", buf)
+}
+
+// prepare makes sure the header has been written and flushes the current
+// block if it contains elements of another type.
+func (e *Encoder) prepare(kind MemberType) error {
+	if e.closed {
+		return fmt.Errorf("encoder has already been closed")
+	}
+	if err := e.start(); err != nil {
+		return err
+	}
+	if kind != e.kind {
+		if err := e.flush(); err != nil {
+			return err
+		}
+		e.kind = kind
+	}
+	return nil
+}
+
+func (e *Encoder) flushIfFull(n int) error {
+	if n < e.BlockSize {
+		return nil
+	}
+	return e.flush()
+}
+
+func (e *Encoder) flush() error {
+	if len(e.nodes) == 0 && len(e.ways) == 0 && len(e.rels) == 0 {
+		return nil
+	}
+	st := newStringTable()
+	dateGran := e.dateGranularity()
+	var groups []*OSMPBF.PrimitiveGroup
+	switch {
+	case len(e.nodes) != 0:
+		// DenseInfo applies to all nodes of a group, so nodes with and
+		// without meta data are written into separate groups.
+		for start := 0; start < len(e.nodes); {
+			end := start + 1
+			for end < len(e.nodes) && (e.nodes[end].Info == nil) == (e.nodes[start].Info == nil) {
+				end++
+			}
+			groups = append(groups, &OSMPBF.PrimitiveGroup{Dense: encodeDenseNodes(e.nodes[start:end], st, dateGran)})
+			start = end
+		}
+	case len(e.ways) != 0:
+		groups = append(groups, &OSMPBF.PrimitiveGroup{Ways: encodeWays(e.ways, st, dateGran)})
+	case len(e.rels) != 0:
+		groups = append(groups, &OSMPBF.PrimitiveGroup{Relations: encodeRelations(e.rels, st, dateGran)})
+	}
+	e.nodes, e.ways, e.rels = e.nodes[:0], e.ways[:0], e.rels[:0]
+
+	pb := &OSMPBF.PrimitiveBlock{
+		Stringtable:     &OSMPBF.StringTable{S: st.s},
+		Primitivegroup:  groups,
+		Granularity:     proto.Int32(coordGranularity),
+		DateGranularity: proto.Int32(int32(dateGran)),
+	}
+	buf, err := proto.Marshal(pb)
+	if err != nil {
+		return err
+	}
+	return e.writeBlob("OSMData", buf)
+}
+
+// dateGranularity returns the coarsest date granularity that represents all
+// timestamps of the current block exactly.
+func (e *Encoder) dateGranularity() int64 {
+	gran := int64(maxDateGranularity)
+	add := func(i *Info) {
+		if i != nil {
+			gran = gcd(gran, toMillis(i.Timestamp)%1000)
+		}
+	}
+	for _, n := range e.nodes {
+		add(n.Info)
+	}
+	for _, w := range e.ways {
+		add(w.Info)
+	}
+	for _, r := range e.rels {
+		add(r.Info)
+	}
+	return gran
+}
+
+func gcd(a, b int64) int64 {
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func (e *Encoder) writeBlob(typ string, data []byte) error {
+	var zbuf bytes.Buffer
+	zw := zlib.NewWriter(&zbuf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	blob := &OSMPBF.Blob{
+		RawSize:  proto.Int32(int32(len(data))),
+		ZlibData: zbuf.Bytes(),
+	}
+	blobBuf, err := proto.Marshal(blob)
+	if err != nil {
+		return err
+	}
+	header := &OSMPBF.BlobHeader{
+		Type:     proto.String(typ),
+		Datasize: proto.Int32(int32(len(blobBuf))),
+	}
+	headerBuf, err := proto.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	sizeBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBuf, uint32(len(headerBuf)))
+	for _, b := range [][]byte{sizeBuf, headerBuf, blobBuf} {
+		if _, err := e.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringTable collects the strings of a block. Index 0 is reserved as
+// delimiter, so every string (including the empty one) gets an index > 0.
+type stringTable struct {
+	s   []string
+	idx map[string]int
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{s: []string{""}, idx: map[string]int{}}
+}
+
+func (st *stringTable) index(s string) int {
+	if i, ok := st.idx[s]; ok {
+		return i
+	}
+	i := len(st.s)
+	st.s = append(st.s, s)
+	st.idx[s] = i
+	return i
+}
+
+func toCoord(deg float64) int64 {
+	return int64(math.Round(deg * 1e9 / coordGranularity))
+}
+
+// toMillis returns the milliseconds since the Unix epoch. Unlike UnixNano, it
+// does not overflow for the zero time.
+func toMillis(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond()/int(time.Millisecond))
+}
+
+func toDate(i *Info, gran int64) int64 {
+	return toMillis(i.Timestamp) / gran
+}
+
+// encodeDenseNodes encodes nodes as dense group. Either all or none of the
+// nodes need to have Info.
+func encodeDenseNodes(nodes []Node, st *stringTable, dateGran int64) *OSMPBF.DenseNodes {
+	dn := &OSMPBF.DenseNodes{
+		Id:  make([]int64, len(nodes)),
+		Lat: make([]int64, len(nodes)),
+		Lon: make([]int64, len(nodes)),
+	}
+	var (
+		hasTags, hasInfo bool
+		id, lat, lon     int64
+	)
+	for _, n := range nodes {
+		hasTags = hasTags || len(n.Tags) != 0
+	}
+	hasInfo = len(nodes) != 0 && nodes[0].Info != nil
+	if hasInfo {
+		dn.Denseinfo = &OSMPBF.DenseInfo{}
+	}
+
+	var (
+		ts, changeset int64
+		uid, userSid  int32
+	)
+	for i, n := range nodes {
+		dn.Id[i] = n.ID - id
+		id = n.ID
+		nLat, nLon := toCoord(n.Lat), toCoord(n.Lon)
+		dn.Lat[i], dn.Lon[i] = nLat-lat, nLon-lon
+		lat, lon = nLat, nLon
+
+		if hasTags {
+			for _, k := range sortedKeys(n.Tags) {
+				dn.KeysVals = append(dn.KeysVals, int32(st.index(k)), int32(st.index(n.Tags[k])))
+			}
+			dn.KeysVals = append(dn.KeysVals, 0)
+		}
+
+		if hasInfo {
+			info := n.Info
+			di := dn.Denseinfo
+			nTs, nUserSid := toDate(info, dateGran), int32(st.index(info.User))
+			di.Version = append(di.Version, int32(info.Version))
+			di.Timestamp = append(di.Timestamp, nTs-ts)
+			di.Changeset = append(di.Changeset, info.Changeset-changeset)
+			di.Uid = append(di.Uid, int32(info.UID)-uid)
+			di.UserSid = append(di.UserSid, nUserSid-userSid)
+			di.Visible = append(di.Visible, info.Visible)
+			ts, changeset, uid, userSid = nTs, info.Changeset, int32(info.UID), nUserSid
+		}
+	}
+	return dn
+}
+
+func encodeTags(tags map[string]string, st *stringTable) (keys, vals []uint32) {
+	keys = make([]uint32, 0, len(tags))
+	vals = make([]uint32, 0, len(tags))
+	for _, k := range sortedKeys(tags) {
+		keys = append(keys, uint32(st.index(k)))
+		vals = append(vals, uint32(st.index(tags[k])))
+	}
+	return keys, vals
+}
+
+func encodeInfo(i *Info, st *stringTable, dateGran int64) *OSMPBF.Info {
+	if i == nil {
+		return nil
+	}
+	return &OSMPBF.Info{
+		Version:   proto.Int32(int32(i.Version)),
+		Timestamp: proto.Int64(toDate(i, dateGran)),
+		Changeset: proto.Int64(i.Changeset),
+		Uid:       proto.Int32(int32(i.UID)),
+		UserSid:   proto.Uint32(uint32(st.index(i.User))),
+		Visible:   proto.Bool(i.Visible),
+	}
+}
+
+func encodeWays(ways []Way, st *stringTable, dateGran int64) []*OSMPBF.Way {
+	out := make([]*OSMPBF.Way, len(ways))
+	for i, w := range ways {
+		pw := &OSMPBF.Way{
+			Id:   proto.Int64(w.ID),
+			Info: encodeInfo(w.Info, st, dateGran),
+			Refs: make([]int64, len(w.NodeIDs)),
+		}
+		pw.Keys, pw.Vals = encodeTags(w.Tags, st)
+		var prev int64
+		for j, id := range w.NodeIDs {
+			pw.Refs[j] = id - prev
+			prev = id
+		}
+		out[i] = pw
+	}
+	return out
+}
+
+func encodeRelations(rels []Relation, st *stringTable, dateGran int64) []*OSMPBF.Relation {
+	out := make([]*OSMPBF.Relation, len(rels))
+	for i, r := range rels {
+		pr := &OSMPBF.Relation{
+			Id:       proto.Int64(r.ID),
+			Info:     encodeInfo(r.Info, st, dateGran),
+			RolesSid: make([]int32, len(r.Members)),
+			Memids:   make([]int64, len(r.Members)),
+			Types:    make([]OSMPBF.Relation_MemberType, len(r.Members)),
+		}
+		pr.Keys, pr.Vals = encodeTags(r.Tags, st)
+		var prev int64
+		for j, m := range r.Members {
+			pr.RolesSid[j] = int32(st.index(m.Role))
+			pr.Memids[j] = m.ID - prev
+			prev = m.ID
+			switch m.Type {
+			case NodeType:
+				pr.Types[j] = OSMPBF.Relation_NODE
+			case WayType:
+				pr.Types[j] = OSMPBF.Relation_WAY
+			case RelationType:
+				pr.Types[j] = OSMPBF.Relation_RELATION
+			}
+		}
+		out[i] = pr
+	}
+	return out
+}
@@ -0,0 +1,193 @@
+package gosmparse
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// normalize makes elements from different sources comparable by rounding the
+// coordinates to the OSM precision and converting timestamps to UTC.
+func normalize(cr *cachedReader) {
+	for i := range cr.Nodes {
+		cr.Nodes[i].Lat = math.Round(cr.Nodes[i].Lat*1e7) / 1e7
+		cr.Nodes[i].Lon = math.Round(cr.Nodes[i].Lon*1e7) / 1e7
+		normalizeElement(&cr.Nodes[i].Element)
+	}
+	for i := range cr.Ways {
+		normalizeElement(&cr.Ways[i].Element)
+	}
+	for i := range cr.Rels {
+		normalizeElement(&cr.Rels[i].Element)
+	}
+}
+
+func normalizeElement(e *Element) {
+	if e.Info != nil {
+		e.Info.Timestamp = e.Info.Timestamp.UTC()
+	}
+}
+
+func readFile(t *testing.T, fn string, parse func(*os.File, OSMReader) error) *cachedReader {
+	f, err := os.Open(fn)
+	assert.Nil(t, err)
+	defer f.Close()
+	cr := &cachedReader{}
+	assert.Nil(t, parse(f, cr))
+	normalize(cr)
+	return cr
+}
+
+func parseXMLWithInfo(f *os.File, o OSMReader) error {
+	return NewXMLDecoderWithInfo(f).Parse(o)
+}
+
+func encodePBF(t *testing.T, h Header, cr *cachedReader) *bytes.Buffer {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Header = h
+	enc.BlockSize = 2
+	for _, n := range cr.Nodes {
+		assert.Nil(t, enc.WriteNode(n))
+	}
+	for _, w := range cr.Ways {
+		assert.Nil(t, enc.WriteWay(w))
+	}
+	for _, r := range cr.Rels {
+		assert.Nil(t, enc.WriteRelation(r))
+	}
+	assert.Nil(t, enc.Close())
+	return &buf
+}
+
+type headerCachedReader struct {
+	cachedReader
+	Header Header
+}
+
+func (r *headerCachedReader) ReadHeader(h Header) {
+	r.Header = h
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	orig := readFile(t, "testdata/history.osh", parseXMLWithInfo)
+
+	h := Header{
+		BoundingBox:               &BoundingBox{MinLat: -1.5, MinLon: -2, MaxLat: 1, MaxLon: 2},
+		RequiredFeatures:          []string{FeatureHistorical},
+		WritingProgram:            "test",
+		ReplicationTimestamp:      time.Unix(1554145200, 0),
+		ReplicationSequenceNumber: 42,
+		ReplicationBaseURL:        "https://example.com/replication",
+	}
+	buf := encodePBF(t, h, orig)
+
+	decoded := &headerCachedReader{}
+	dec := NewDecoderWithInfo(buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(decoded))
+	normalize(&decoded.cachedReader)
+
+	assert.Equal(t, orig.Nodes, decoded.Nodes)
+	assert.Equal(t, orig.Ways, decoded.Ways)
+	assert.Equal(t, orig.Rels, decoded.Rels)
+
+	assert.Equal(t, []string{FeatureOsmSchema, FeatureDenseNodes, FeatureHistorical}, decoded.Header.RequiredFeatures)
+	assert.True(t, decoded.Header.HasFeature(FeatureHistorical))
+	assert.Equal(t, h.BoundingBox, decoded.Header.BoundingBox)
+	assert.Equal(t, "test", decoded.Header.WritingProgram)
+	assert.True(t, h.ReplicationTimestamp.Equal(decoded.Header.ReplicationTimestamp))
+	assert.Equal(t, int64(42), decoded.Header.ReplicationSequenceNumber)
+	assert.Equal(t, h.ReplicationBaseURL, decoded.Header.ReplicationBaseURL)
+}
+
+func TestEncoderWithoutInfo(t *testing.T) {
+	orig := readFile(t, "testdata/node_kv.osm", func(f *os.File, o OSMReader) error {
+		return NewXMLDecoder(f).Parse(o)
+	})
+	orig.Nodes = append(orig.Nodes, Node{Element: Element{ID: 4, Tags: map[string]string{"": ""}}, Lat: -89.9999999, Lon: 179.9999999})
+
+	buf := encodePBF(t, Header{}, orig)
+	decoded := &cachedReader{}
+	dec := NewDecoder(buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(decoded))
+	normalize(decoded)
+	assert.Equal(t, orig.Nodes, decoded.Nodes)
+}
+
+func TestEncoderMixedInfo(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tags := map[string]string{"a": "b"}
+	withInfo := func(id int64) Node {
+		return Node{Element: Element{ID: id, Tags: tags, Info: &Info{Version: 2, Timestamp: ts, Changeset: 7, UID: 3, User: "u", Visible: true}}, Lat: 1}
+	}
+	withoutInfo := func(id int64) Node {
+		return Node{Element: Element{ID: id, Tags: tags}, Lat: 2}
+	}
+	orig := []Node{withInfo(1), withoutInfo(2), withoutInfo(3), withInfo(4), withInfo(5)}
+
+	// all nodes end up in the same block
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, n := range orig {
+		assert.Nil(t, enc.WriteNode(n))
+	}
+	assert.Nil(t, enc.Close())
+
+	decoded := &cachedReader{}
+	dec := NewDecoderWithInfo(&buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(decoded))
+	normalize(decoded)
+	assert.Equal(t, orig, decoded.Nodes)
+}
+
+func TestEncoderDateGranularity(t *testing.T) {
+	orig := readFile(t, "testdata/date_granularity.pbf", func(f *os.File, o OSMReader) error {
+		dec := NewDecoderWithInfo(f)
+		dec.Workers = 1
+		return dec.Parse(o)
+	})
+	orig.Nodes = append(orig.Nodes, Node{Element: Element{ID: 3, Tags: map[string]string{}, Info: &Info{
+		Version: 1, Timestamp: time.Date(1969, 12, 31, 23, 59, 58, 250000000, time.UTC), Visible: true,
+	}}})
+	normalize(orig)
+
+	buf := encodePBF(t, Header{}, orig)
+	decoded := &cachedReader{}
+	dec := NewDecoderWithInfo(buf)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(decoded))
+	normalize(decoded)
+	assert.Equal(t, orig.Nodes, decoded.Nodes)
+	assert.Equal(t, orig.Ways, decoded.Ways)
+	assert.Equal(t, orig.Rels, decoded.Rels)
+}
+
+func TestEncoderChoosesDateGranularity(t *testing.T) {
+	info := func(ms int) *Info {
+		return &Info{Timestamp: time.Date(2020, 1, 1, 0, 0, 0, ms*int(time.Millisecond), time.UTC)}
+	}
+	for _, tc := range []struct {
+		millis   []int
+		expected int64
+	}{
+		{nil, 1000},
+		{[]int{0, 0}, 1000},
+		{[]int{500, 0}, 500},
+		{[]int{200, 500}, 100},
+		{[]int{123}, 1},
+	} {
+		e := NewEncoder(nil)
+		e.ways = append(e.ways, Way{})
+		for _, ms := range tc.millis {
+			e.ways = append(e.ways, Way{Element: Element{Info: info(ms)}})
+		}
+		assert.Equal(t, tc.expected, e.dateGranularity(), tc.millis)
+	}
+}
@@ -0,0 +1,159 @@
+package gosmparse
+
+// entity holds a single element of any type. Only the field matching Type is
+// populated.
+type entity struct {
+	Type     MemberType
+	Node     Node
+	Way      Way
+	Relation Relation
+}
+
+func (e *entity) element() *Element {
+	switch e.Type {
+	case NodeType:
+		return &e.Node.Element
+	case WayType:
+		return &e.Way.Element
+	default:
+		return &e.Relation.Element
+	}
+}
+
+func (e *entity) version() int {
+	if info := e.element().Info; info != nil {
+		return info.Version
+	}
+	return 0
+}
+
+func (e *entity) key() entityKey {
+	return entityKey{e.Type, e.element().ID}
+}
+
+// less reports whether e sorts before other, i.e. ordered by type, ID and
+// version.
+func (e *entity) less(other *entity) bool {
+	k, ok := e.key(), other.key()
+	if k != ok {
+		return k.less(ok)
+	}
+	return e.version() < other.version()
+}
+
+func (e *entity) write(w OSMWriter) error {
+	switch e.Type {
+	case NodeType:
+		return w.WriteNode(e.Node)
+	case WayType:
+		return w.WriteWay(e.Way)
+	default:
+		return w.WriteRelation(e.Relation)
+	}
+}
+
+// entityKey identifies an element across all types.
+type entityKey struct {
+	Type MemberType
+	ID   int64
+}
+
+// less reports whether k sorts before other, i.e. ordered by type first and
+// by ID second.
+func (k entityKey) less(other entityKey) bool {
+	if k.Type != other.Type {
+		return k.Type < other.Type
+	}
+	return k.ID < other.ID
+}
+
+// equal reports whether e and other are the same element with the same
+// content, including their meta data.
+func (e *entity) equal(other *entity) bool {
+	return e.key() == other.key() && e.changes(other) == 0
+}
+
+// changes compares the content of e and other, which need to be of the same
+// type.
+func (e *entity) changes(other *entity) DiffChanges {
+	var c DiffChanges
+	a, b := e.element(), other.element()
+	if !tagsEqual(a.Tags, b.Tags) {
+		c |= ChangedTags
+	}
+	if !infoEqual(a.Info, b.Info) {
+		c |= ChangedInfo
+	}
+	switch e.Type {
+	case NodeType:
+		if e.Node.Lat != other.Node.Lat || e.Node.Lon != other.Node.Lon {
+			c |= ChangedLocation
+		}
+	case WayType:
+		if !idsEqual(e.Way.NodeIDs, other.Way.NodeIDs) {
+			c |= ChangedNodes
+		}
+	default:
+		if !membersEqual(e.Relation.Members, other.Relation.Members) {
+			c |= ChangedMembers
+		}
+	}
+	return c
+}
+
+// value returns the element as Node, Way or Relation.
+func (e *entity) value() interface{} {
+	switch e.Type {
+	case NodeType:
+		return e.Node
+	case WayType:
+		return e.Way
+	default:
+		return e.Relation
+	}
+}
+
+func tagsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func infoEqual(a, b *Info) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Version == b.Version && a.Timestamp.Equal(b.Timestamp) &&
+		a.Changeset == b.Changeset && a.UID == b.UID && a.User == b.User &&
+		a.Visible == b.Visible
+}
+
+func idsEqual(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func membersEqual(a, b []RelationMember) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
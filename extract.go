@@ -0,0 +1,486 @@
+package gosmparse
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Region is an area that data can be extracted for, see ExtractDecoder.
+type Region interface {
+	// Contains reports whether ll lies inside the region.
+	Contains(ll LatLon) bool
+	// Bounds returns the bounding box of the region.
+	Bounds() BoundingBox
+}
+
+// Contains reports whether ll lies inside b, including its edges.
+func (b BoundingBox) Contains(ll LatLon) bool {
+	return ll.Lat >= b.MinLat && ll.Lat <= b.MaxLat && ll.Lon >= b.MinLon && ll.Lon <= b.MaxLon
+}
+
+// Bounds returns b, so BoundingBox implements Region.
+func (b BoundingBox) Bounds() BoundingBox {
+	return b
+}
+
+// Contains reports whether ll lies inside one of the outer rings of mp, but
+// not inside one of its inner rings.
+func (mp MultiPolygon) Contains(ll LatLon) bool {
+outer:
+	for _, p := range mp {
+		if !pointInRing(ll, p.Outer) {
+			continue
+		}
+		for _, inner := range p.Inners {
+			if pointInRing(ll, inner) {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Bounds returns the bounding box of all outer rings of mp.
+func (mp MultiPolygon) Bounds() BoundingBox {
+	b := BoundingBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	for _, p := range mp {
+		for _, ll := range p.Outer {
+			b.MinLat = math.Min(b.MinLat, ll.Lat)
+			b.MinLon = math.Min(b.MinLon, ll.Lon)
+			b.MaxLat = math.Max(b.MaxLat, ll.Lat)
+			b.MaxLon = math.Max(b.MaxLon, ll.Lon)
+		}
+	}
+	return b
+}
+
+// ReadPoly reads a polygon in the Osmosis polygon filter file format (.poly).
+// Sections whose name starts with "!" are holes; they are assigned to the
+// polygon that contains them.
+func ReadPoly(r io.Reader) (MultiPolygon, error) {
+	sc := bufio.NewScanner(r)
+	line := 0
+	next := func() (string, bool) {
+		for sc.Scan() {
+			line++
+			if s := strings.TrimSpace(sc.Text()); s != "" {
+				return s, true
+			}
+		}
+		return "", false
+	}
+
+	if _, ok := next(); !ok {
+		return nil, fmt.Errorf("poly: missing name")
+	}
+	var (
+		mp    MultiPolygon
+		holes []Ring
+	)
+	for {
+		section, ok := next()
+		if !ok {
+			if err := sc.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("poly: missing END")
+		}
+		if section == "END" {
+			break
+		}
+		var ring Ring
+		for {
+			s, ok := next()
+			if !ok {
+				return nil, fmt.Errorf("poly: section %s: missing END", section)
+			}
+			if s == "END" {
+				break
+			}
+			fields := strings.Fields(s)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("poly: line %d: expected two coordinates", line)
+			}
+			lon, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("poly: line %d: %v", line, err)
+			}
+			lat, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("poly: line %d: %v", line, err)
+			}
+			ring = append(ring, LatLon{Lat: lat, Lon: lon})
+		}
+		ring, err := closeRing(ring)
+		if err != nil {
+			return nil, fmt.Errorf("poly: section %s: %v", section, err)
+		}
+		if strings.HasPrefix(section, "!") {
+			holes = append(holes, ring)
+		} else {
+			mp = append(mp, Polygon{Outer: ring})
+		}
+	}
+	if len(mp) == 0 {
+		return nil, fmt.Errorf("poly: no outer ring")
+	}
+
+	for _, h := range holes {
+		for i := range mp {
+			if pointInRing(h[0], mp[i].Outer) {
+				mp[i].Inners = append(mp[i].Inners, h)
+				break
+			}
+		}
+	}
+	return mp, nil
+}
+
+// ReadGeoJSONRegion reads a region from a GeoJSON Polygon or MultiPolygon
+// geometry, or from a Feature or FeatureCollection with such geometries.
+func ReadGeoJSONRegion(r io.Reader) (MultiPolygon, error) {
+	var obj geoJSONObject
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, err
+	}
+	mp, err := obj.multiPolygon()
+	if err != nil {
+		return nil, err
+	}
+	if len(mp) == 0 {
+		return nil, fmt.Errorf("geojson: no polygon found")
+	}
+	return mp, nil
+}
+
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Features    []geoJSONObject `json:"features"`
+}
+
+func (o *geoJSONObject) multiPolygon() (MultiPolygon, error) {
+	switch o.Type {
+	case "FeatureCollection":
+		var mp MultiPolygon
+		for _, f := range o.Features {
+			p, err := f.multiPolygon()
+			if err != nil {
+				return nil, err
+			}
+			mp = append(mp, p...)
+		}
+		return mp, nil
+	case "Feature":
+		if o.Geometry == nil {
+			return nil, nil
+		}
+		return o.Geometry.multiPolygon()
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(o.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("geojson: %v", err)
+		}
+		p, err := geoJSONPolygon(coords)
+		if err != nil {
+			return nil, err
+		}
+		return MultiPolygon{p}, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(o.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("geojson: %v", err)
+		}
+		var mp MultiPolygon
+		for _, c := range coords {
+			p, err := geoJSONPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			mp = append(mp, p)
+		}
+		return mp, nil
+	}
+	return nil, fmt.Errorf("geojson: unsupported type %q", o.Type)
+}
+
+func geoJSONPolygon(coords [][][]float64) (Polygon, error) {
+	var p Polygon
+	if len(coords) == 0 {
+		return p, fmt.Errorf("geojson: polygon without rings")
+	}
+	for i, rc := range coords {
+		ring := make(Ring, len(rc))
+		for j, pos := range rc {
+			if len(pos) < 2 {
+				return p, fmt.Errorf("geojson: invalid position")
+			}
+			ring[j] = LatLon{Lat: pos[1], Lon: pos[0]}
+		}
+		ring, err := closeRing(ring)
+		if err != nil {
+			return p, fmt.Errorf("geojson: %v", err)
+		}
+		if i == 0 {
+			p.Outer = ring
+		} else {
+			p.Inners = append(p.Inners, ring)
+		}
+	}
+	return p, nil
+}
+
+// closeRing appends the first location to r if r is not closed yet.
+func closeRing(r Ring) (Ring, error) {
+	if len(r) < 3 {
+		return nil, fmt.Errorf("ring with less than three locations")
+	}
+	if r[0] != r[len(r)-1] {
+		r = append(r, r[0])
+	}
+	return r, nil
+}
+
+// ExtractStrategy determines which elements are part of an extract.
+type ExtractStrategy int
+
+const (
+	// ExtractSimple includes the nodes inside the region, the ways that
+	// reference any of them and the relations that reference any included
+	// element. Ways at the border are incomplete.
+	ExtractSimple ExtractStrategy = iota
+	// ExtractCompleteWays is like ExtractSimple, but adds all nodes of the
+	// included ways.
+	ExtractCompleteWays
+	// ExtractSmart is like ExtractCompleteWays, but adds all member ways of
+	// included multipolygon relations together with their nodes, so the
+	// areas are complete as well.
+	ExtractSmart
+)
+
+// String returns the name of the strategy as used by osmium.
+func (s ExtractStrategy) String() string {
+	switch s {
+	case ExtractSimple:
+		return "simple"
+	case ExtractCompleteWays:
+		return "complete_ways"
+	case ExtractSmart:
+		return "smart"
+	}
+	return fmt.Sprintf("ExtractStrategy(%d)", int(s))
+}
+
+// An ExtractDecoder parses a PBF file in multiple passes and delivers only the
+// elements that belong to Region according to Strategy. The bounding box of
+// the header is replaced with the bounds of Region.
+type ExtractDecoder struct {
+	Region   Region
+	Strategy ExtractStrategy
+	// WithInfo populates the Info field of the elements, like
+	// NewDecoderWithInfo does.
+	WithInfo bool
+	// Workers is the number of workers of the pass that delivers the elements,
+	// see Decoder. Set it to 1 to receive the elements in file order.
+	Workers int
+
+	r io.ReadSeeker
+}
+
+// NewExtractDecoder returns a new decoder that reads from r, which needs to be
+// seekable for the additional passes.
+func NewExtractDecoder(r io.ReadSeeker, region Region) *ExtractDecoder {
+	return &ExtractDecoder{r: r, Region: region}
+}
+
+// Parse runs all passes and streams the extracted elements into the given
+// OSMReader. If o implements HeaderReader, the header is delivered before the
+// elements.
+func (d *ExtractDecoder) Parse(o OSMReader) error {
+	ep := &extractPass{
+		d:          d,
+		nodes:      newIDSet(),
+		extraNodes: newIDSet(),
+		ways:       newIDSet(),
+		rels:       newIDSet(),
+		mpWays:     newIDSet(),
+	}
+
+	ep.stage = extractNodes
+	if err := d.pass(ep); err != nil {
+		return err
+	}
+	ep.stage = extractWays
+	if err := d.pass(ep); err != nil {
+		return err
+	}
+	// Relations may reference relations that are stored after them, so the
+	// relation pass is repeated until no new relations are found.
+	ep.stage = extractRelations
+	for {
+		n := ep.rels.len()
+		if err := d.pass(ep); err != nil {
+			return err
+		}
+		if ep.rels.len() == n {
+			break
+		}
+	}
+	if d.Strategy == ExtractSmart && ep.mpWays.len() > 0 {
+		ep.stage = extractAreaWays
+		if err := d.pass(ep); err != nil {
+			return err
+		}
+	}
+
+	ep.stage = extractOutput
+	ep.o = o
+	dec := d.decoder()
+	dec.Workers = d.Workers
+	return dec.Parse(ep)
+}
+
+func (d *ExtractDecoder) pass(o OSMReader) error {
+	if _, err := d.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := NewDecoder(d.r).Parse(o); err != nil {
+		return err
+	}
+	_, err := d.r.Seek(0, io.SeekStart)
+	return err
+}
+
+func (d *ExtractDecoder) decoder() *Decoder {
+	if d.WithInfo {
+		return NewDecoderWithInfo(d.r)
+	}
+	return NewDecoder(d.r)
+}
+
+type extractStage int
+
+const (
+	extractNodes extractStage = iota
+	extractWays
+	extractRelations
+	extractAreaWays
+	extractOutput
+)
+
+// extractPass implements all passes of ExtractDecoder, depending on stage.
+type extractPass struct {
+	d     *ExtractDecoder
+	o     OSMReader
+	stage extractStage
+
+	// nodes are the nodes inside the region, extraNodes the ones added to
+	// complete ways.
+	nodes, extraNodes *idSet
+	ways, rels        *idSet
+	// mpWays are the member ways of included multipolygons.
+	mpWays *idSet
+}
+
+func (p *extractPass) ReadHeader(h Header) {
+	if p.stage != extractOutput {
+		return
+	}
+	if hr, ok := p.o.(HeaderReader); ok {
+		b := p.d.Region.Bounds()
+		h.BoundingBox = &b
+		hr.ReadHeader(h)
+	}
+}
+
+func (p *extractPass) ReadNode(n Node) {
+	switch p.stage {
+	case extractNodes:
+		if n.Info != nil && !n.Info.Visible {
+			return
+		}
+		if p.d.Region.Contains(LatLon{Lat: n.Lat, Lon: n.Lon}) {
+			p.nodes.add(n.ID)
+		}
+	case extractOutput:
+		if p.nodes.has(n.ID) || p.extraNodes.has(n.ID) {
+			p.o.ReadNode(n)
+		}
+	}
+}
+
+func (p *extractPass) ReadWay(w Way) {
+	switch p.stage {
+	case extractWays:
+		for _, id := range w.NodeIDs {
+			if p.nodes.has(id) {
+				p.ways.add(w.ID)
+				if p.d.Strategy != ExtractSimple {
+					p.addNodes(w)
+				}
+				return
+			}
+		}
+	case extractAreaWays:
+		if p.mpWays.has(w.ID) {
+			p.ways.add(w.ID)
+			p.addNodes(w)
+		}
+	case extractOutput:
+		if p.ways.has(w.ID) {
+			p.o.ReadWay(w)
+		}
+	}
+}
+
+func (p *extractPass) addNodes(w Way) {
+	for _, id := range w.NodeIDs {
+		if !p.nodes.has(id) {
+			p.extraNodes.add(id)
+		}
+	}
+}
+
+func (p *extractPass) ReadRelation(r Relation) {
+	switch p.stage {
+	case extractRelations:
+		if !p.rels.has(r.ID) && !p.referencesIncluded(r) {
+			return
+		}
+		p.rels.add(r.ID)
+		if p.d.Strategy == ExtractSmart && r.Tags["type"] == "multipolygon" {
+			p.addAreaWays(r)
+		}
+	case extractOutput:
+		if p.rels.has(r.ID) {
+			p.o.ReadRelation(r)
+		}
+	}
+}
+
+func (p *extractPass) referencesIncluded(r Relation) bool {
+	for _, m := range r.Members {
+		switch {
+		case m.Type == NodeType && p.nodes.has(m.ID),
+			m.Type == WayType && p.ways.has(m.ID),
+			m.Type == RelationType && p.rels.has(m.ID):
+			return true
+		}
+	}
+	return false
+}
+
+func (p *extractPass) addAreaWays(r Relation) {
+	for _, m := range r.Members {
+		if m.Type == WayType && !p.ways.has(m.ID) {
+			p.mpWays.add(m.ID)
+		}
+	}
+}
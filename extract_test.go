@@ -0,0 +1,137 @@
+package gosmparse
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPoly = `test
+first_area
+     0.0     0.0
+     10.0    0.0
+     10.0    10.0
+     0.0     10.0
+END
+!hole
+     2.0     2.0
+     8.0     2.0
+     8.0     8.0
+     2.0     8.0
+     2.0     2.0
+END
+second_area
+     20.0    20.0
+     21.0    20.0
+     21.0    21.0
+END
+END
+`
+
+func TestReadPoly(t *testing.T) {
+	mp, err := ReadPoly(strings.NewReader(testPoly))
+	assert.Nil(t, err)
+	assert.Len(t, mp, 2)
+	assert.Len(t, mp[0].Outer, 5)
+	assert.Equal(t, mp[0].Outer[0], mp[0].Outer[4])
+	assert.Len(t, mp[0].Inners, 1)
+	assert.Len(t, mp[1].Inners, 0)
+
+	assert.True(t, mp.Contains(LatLon{Lat: 1, Lon: 1}))
+	assert.False(t, mp.Contains(LatLon{Lat: 5, Lon: 5}))
+	assert.True(t, mp.Contains(LatLon{Lat: 20.2, Lon: 20.5}))
+	assert.False(t, mp.Contains(LatLon{Lat: 15, Lon: 15}))
+	assert.Equal(t, BoundingBox{MinLat: 0, MinLon: 0, MaxLat: 21, MaxLon: 21}, mp.Bounds())
+}
+
+func TestReadPolyErrors(t *testing.T) {
+	for _, poly := range []string{
+		"",
+		"name\n1\n0 0\n1 0\n1 1\nEND\n",
+		"name\n1\n0 0\n1 0\nEND\nEND\n",
+		"name\n1\n0 0\n1 x\n1 1\nEND\nEND\n",
+		"name\n!1\n0 0\n1 0\n1 1\nEND\nEND\n",
+	} {
+		_, err := ReadPoly(strings.NewReader(poly))
+		assert.NotNil(t, err, poly)
+	}
+}
+
+func TestReadGeoJSONRegion(t *testing.T) {
+	for _, js := range []string{
+		`{"type": "Polygon", "coordinates": [[[0,0],[10,0],[10,10],[0,10],[0,0]], [[2,2],[8,2],[8,8],[2,8]]]}`,
+		`{"type": "Feature", "properties": {}, "geometry": {"type": "MultiPolygon", "coordinates": [[[[0,0],[10,0],[10,10],[0,10],[0,0]], [[2,2],[8,2],[8,8],[2,8],[2,2]]]]}}`,
+		`{"type": "FeatureCollection", "features": [{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0,0,5],[10,0,5],[10,10,5],[0,10,5]], [[2,2],[8,2],[8,8],[2,8]]]}}]}`,
+	} {
+		mp, err := ReadGeoJSONRegion(strings.NewReader(js))
+		assert.Nil(t, err, js)
+		assert.Len(t, mp, 1)
+		assert.True(t, mp.Contains(LatLon{Lat: 1, Lon: 9}))
+		assert.False(t, mp.Contains(LatLon{Lat: 5, Lon: 5}))
+	}
+
+	for _, js := range []string{
+		`{"type": "Point", "coordinates": [1, 2]}`,
+		`{"type": "FeatureCollection", "features": []}`,
+		`{"type": "Polygon", "coordinates": [[[0,0],[1,1]]]}`,
+	} {
+		_, err := ReadGeoJSONRegion(strings.NewReader(js))
+		assert.NotNil(t, err, js)
+	}
+}
+
+func extractTestFile(t *testing.T) *bytes.Reader {
+	node := func(id int64, lat, lon float64) Node {
+		return Node{Element: Element{ID: id}, Lat: lat, Lon: lon}
+	}
+	cr := &cachedReader{
+		Nodes: []Node{
+			node(1, 0.5, 0.5), node(2, 0.5, 1.5), node(3, 0.6, 0.6),
+			node(4, 2, 2), node(5, 3, 3), node(6, 3, 4),
+		},
+		Ways: []Way{
+			{Element: Element{ID: 10}, NodeIDs: []int64{1, 2}},
+			{Element: Element{ID: 11}, NodeIDs: []int64{4, 5}},
+			{Element: Element{ID: 12}, NodeIDs: []int64{5, 6}},
+		},
+		Rels: []Relation{
+			{Element: Element{ID: 20, Tags: map[string]string{"type": "multipolygon"}}, Members: []RelationMember{
+				{ID: 10, Type: WayType, Role: "outer"}, {ID: 12, Type: WayType, Role: "outer"},
+			}},
+			{Element: Element{ID: 21}, Members: []RelationMember{{ID: 20, Type: RelationType}}},
+			{Element: Element{ID: 22}, Members: []RelationMember{{ID: 11, Type: WayType}}},
+			// references node 3, but comes after relation 24 which references it
+			{Element: Element{ID: 23}, Members: []RelationMember{{ID: 3, Type: NodeType}}},
+			{Element: Element{ID: 24}, Members: []RelationMember{{ID: 25, Type: RelationType}}},
+			{Element: Element{ID: 25}, Members: []RelationMember{{ID: 3, Type: NodeType}}},
+		},
+	}
+	return bytes.NewReader(encodePBF(t, Header{WritingProgram: "test"}, cr).Bytes())
+}
+
+func TestExtractDecoder(t *testing.T) {
+	bbox := BoundingBox{MinLat: 0, MinLon: 0, MaxLat: 1, MaxLon: 1}
+	for _, tc := range []struct {
+		strategy          ExtractStrategy
+		nodes, ways, rels []int64
+	}{
+		{ExtractSimple, []int64{1, 3}, []int64{10}, []int64{20, 21, 23, 24, 25}},
+		{ExtractCompleteWays, []int64{1, 2, 3}, []int64{10}, []int64{20, 21, 23, 24, 25}},
+		{ExtractSmart, []int64{1, 2, 3, 5, 6}, []int64{10, 12}, []int64{20, 21, 23, 24, 25}},
+	} {
+		cr := &headerCachedReader{}
+		dec := NewExtractDecoder(extractTestFile(t), bbox)
+		dec.Strategy = tc.strategy
+		dec.Workers = 1
+		assert.Nil(t, dec.Parse(cr), tc.strategy.String())
+
+		nodes, ways, rels := elementIDs(&cr.cachedReader)
+		assert.Equal(t, tc.nodes, nodes, tc.strategy.String())
+		assert.Equal(t, tc.ways, ways, tc.strategy.String())
+		assert.Equal(t, tc.rels, rels, tc.strategy.String())
+		assert.Equal(t, &bbox, cr.Header.BoundingBox)
+		assert.Equal(t, "test", cr.Header.WritingProgram)
+	}
+}
@@ -0,0 +1,322 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// A TagFilter matches elements by their type and tags. It consists of
+// expressions in the form
+//
+//	[TYPES/]KEY[=VALUE[,VALUE...]]
+//	[TYPES/]KEY!=VALUE[,VALUE...]
+//
+// TYPES is a combination of the letters n, w and r and restricts the
+// expression to nodes, ways and relations; without it, all types are matched.
+// An expression without values matches elements that have the key, with "="
+// the value needs to be one of the given values and with "!=" it must not be
+// any of them. Keys and values may contain "*" as wildcard for any number of
+// characters, e.g. "name:*" or "*_link". An element matches the filter if it
+// matches any of the expressions.
+type TagFilter struct {
+	exprs []filterExpr
+}
+
+type filterExpr struct {
+	types  [3]bool
+	key    string
+	values []string
+	negate bool
+}
+
+// ParseTagFilter parses the given expressions into a TagFilter.
+func ParseTagFilter(exprs ...string) (*TagFilter, error) {
+	f := &TagFilter{}
+	for _, s := range exprs {
+		e, err := parseFilterExpr(s)
+		if err != nil {
+			return nil, err
+		}
+		f.exprs = append(f.exprs, e)
+	}
+	return f, nil
+}
+
+func parseFilterExpr(s string) (filterExpr, error) {
+	var e filterExpr
+	rest := strings.TrimSpace(s)
+	if i := strings.IndexByte(rest, '/'); i > 0 && strings.Trim(rest[:i], "nwr") == "" {
+		for _, c := range rest[:i] {
+			e.types[strings.IndexRune("nwr", c)] = true
+		}
+		rest = rest[i+1:]
+	} else {
+		e.types = [3]bool{true, true, true}
+	}
+
+	var values string
+	hasValues := false
+	if i := strings.Index(rest, "!="); i >= 0 {
+		e.key, values, e.negate, hasValues = rest[:i], rest[i+2:], true, true
+	} else if i := strings.IndexByte(rest, '='); i >= 0 {
+		e.key, values, hasValues = rest[:i], rest[i+1:], true
+	} else {
+		e.key = rest
+	}
+	if e.key == "" {
+		return e, fmt.Errorf("filter expression %q: missing key", s)
+	}
+	if hasValues {
+		if values == "" {
+			return e, fmt.Errorf("filter expression %q: missing value", s)
+		}
+		e.values = strings.Split(values, ",")
+	}
+	return e, nil
+}
+
+// Match reports whether an element of type t with the given tags matches f.
+func (f *TagFilter) Match(t MemberType, tags map[string]string) bool {
+	for _, e := range f.exprs {
+		if e.match(t, tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *filterExpr) match(t MemberType, tags map[string]string) bool {
+	if t < NodeType || t > RelationType || !e.types[t] {
+		return false
+	}
+	if !strings.Contains(e.key, "*") {
+		v, ok := tags[e.key]
+		return ok && e.matchValue(v)
+	}
+	for k, v := range tags {
+		if matchWildcard(e.key, k) && e.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *filterExpr) matchValue(v string) bool {
+	if e.values == nil {
+		return true
+	}
+	for _, pattern := range e.values {
+		if matchWildcard(pattern, v) {
+			return !e.negate
+		}
+	}
+	return e.negate
+}
+
+// matchWildcard reports whether s matches pattern, in which "*" matches any
+// number of characters.
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
+// A FilterDecoder parses a PBF file and delivers only the elements that match
+// Filter. If Complete is set, the output is made referentially complete,
+// which needs multiple passes over the file.
+type FilterDecoder struct {
+	Filter *TagFilter
+	// Complete adds the nodes of matching ways and the members of matching
+	// relations, including the members of relations that are members
+	// themselves.
+	Complete bool
+	// WithInfo populates the Info field of the elements, like
+	// NewDecoderWithInfo does.
+	WithInfo bool
+	// Workers is the number of workers of the pass that delivers the elements,
+	// see Decoder. Set it to 1 to receive the elements in file order.
+	Workers int
+
+	r io.ReadSeeker
+}
+
+// NewFilterDecoder returns a new decoder that reads from r, which needs to be
+// seekable if Complete is set.
+func NewFilterDecoder(r io.ReadSeeker, f *TagFilter) *FilterDecoder {
+	return &FilterDecoder{r: r, Filter: f}
+}
+
+// Parse streams the matching elements into the given OSMReader. If o
+// implements HeaderReader, the header is delivered before the elements.
+func (d *FilterDecoder) Parse(o OSMReader) error {
+	fp := &filterPass{o: o, f: d.Filter}
+	if d.Complete {
+		if err := d.collectRefs(fp); err != nil {
+			return err
+		}
+	}
+	dec := d.decoder()
+	dec.Workers = d.Workers
+	return dec.Parse(fp)
+}
+
+// collectRefs fills the ID sets of fp with all elements that are referenced
+// by matching elements.
+func (d *FilterDecoder) collectRefs(fp *filterPass) error {
+	fp.nodes, fp.ways, fp.rels = newIDSet(), newIDSet(), newIDSet()
+	// Every pass over the relations adds the members of the relations found in
+	// the previous pass, until no new relations are found.
+	for {
+		n := fp.rels.len()
+		if err := d.pass(&relationRefPass{fp: fp}); err != nil {
+			return err
+		}
+		if fp.rels.len() == n {
+			break
+		}
+	}
+	return d.pass(&wayRefPass{fp: fp})
+}
+
+func (d *FilterDecoder) pass(o OSMReader) error {
+	if _, err := d.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := NewDecoder(d.r).Parse(o); err != nil {
+		return err
+	}
+	_, err := d.r.Seek(0, io.SeekStart)
+	return err
+}
+
+func (d *FilterDecoder) decoder() *Decoder {
+	if d.WithInfo {
+		return NewDecoderWithInfo(d.r)
+	}
+	return NewDecoder(d.r)
+}
+
+// filterPass forwards matching and referenced elements. The ID sets are nil
+// unless referenced elements are added.
+type filterPass struct {
+	o                 OSMReader
+	f                 *TagFilter
+	nodes, ways, rels *idSet
+}
+
+func (p *filterPass) ReadHeader(h Header) {
+	if hr, ok := p.o.(HeaderReader); ok {
+		hr.ReadHeader(h)
+	}
+}
+
+func (p *filterPass) ReadNode(n Node) {
+	if p.f.Match(NodeType, n.Tags) || p.nodes.has(n.ID) {
+		p.o.ReadNode(n)
+	}
+}
+
+func (p *filterPass) ReadWay(w Way) {
+	if p.f.Match(WayType, w.Tags) || p.ways.has(w.ID) {
+		p.o.ReadWay(w)
+	}
+}
+
+func (p *filterPass) ReadRelation(r Relation) {
+	if p.f.Match(RelationType, r.Tags) || p.rels.has(r.ID) {
+		p.o.ReadRelation(r)
+	}
+}
+
+// relationRefPass adds the members of matching and referenced relations.
+type relationRefPass struct {
+	fp *filterPass
+}
+
+func (p *relationRefPass) ReadNode(Node) {}
+
+func (p *relationRefPass) ReadWay(Way) {}
+
+func (p *relationRefPass) ReadRelation(r Relation) {
+	fp := p.fp
+	if !fp.f.Match(RelationType, r.Tags) && !fp.rels.has(r.ID) {
+		return
+	}
+	for _, m := range r.Members {
+		switch m.Type {
+		case NodeType:
+			fp.nodes.add(m.ID)
+		case WayType:
+			fp.ways.add(m.ID)
+		case RelationType:
+			fp.rels.add(m.ID)
+		}
+	}
+}
+
+// wayRefPass adds the nodes of matching and referenced ways.
+type wayRefPass struct {
+	fp *filterPass
+}
+
+func (p *wayRefPass) ReadNode(Node) {}
+
+func (p *wayRefPass) ReadWay(w Way) {
+	fp := p.fp
+	if !fp.f.Match(WayType, w.Tags) && !fp.ways.has(w.ID) {
+		return
+	}
+	for _, id := range w.NodeIDs {
+		fp.nodes.add(id)
+	}
+}
+
+func (p *wayRefPass) ReadRelation(Relation) {}
+
+// idSet is an IDSet that is safe for concurrent use. A nil set is empty.
+type idSet struct {
+	mtx sync.RWMutex
+	ids IDSet
+}
+
+func newIDSet() *idSet {
+	return &idSet{}
+}
+
+func (s *idSet) add(id int64) {
+	s.mtx.Lock()
+	s.ids.Add(id)
+	s.mtx.Unlock()
+}
+
+func (s *idSet) has(id int64) bool {
+	if s == nil {
+		return false
+	}
+	s.mtx.RLock()
+	ok := s.ids.Has(id)
+	s.mtx.RUnlock()
+	return ok
+}
+
+func (s *idSet) len() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.ids.Len()
+}
@@ -0,0 +1,123 @@
+package gosmparse
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagFilterMatch(t *testing.T) {
+	tags := map[string]string{"highway": "primary_link", "name:de": "Weg", "oneway": "yes"}
+	for _, tc := range []struct {
+		expr  string
+		t     MemberType
+		match bool
+	}{
+		{"highway", WayType, true},
+		{"w/highway", WayType, true},
+		{"w/highway", NodeType, false},
+		{"nr/highway", RelationType, true},
+		{"highway=primary,secondary", WayType, false},
+		{"highway=primary*,secondary", WayType, true},
+		{"highway=*_link", WayType, true},
+		{"highway!=primary_link", WayType, false},
+		{"highway!=motorway", WayType, true},
+		{"railway!=rail", WayType, false},
+		{"name:*", NodeType, true},
+		{"name:*=W*g", NodeType, true},
+		{"*:de=Pfad", NodeType, false},
+		{"amenity", NodeType, false},
+	} {
+		f, err := ParseTagFilter(tc.expr)
+		assert.Nil(t, err, tc.expr)
+		assert.Equal(t, tc.match, f.Match(tc.t, tags), tc.expr)
+	}
+
+	f, err := ParseTagFilter("n/amenity", "w/highway")
+	assert.Nil(t, err)
+	assert.True(t, f.Match(WayType, tags))
+	assert.False(t, f.Match(NodeType, tags))
+}
+
+func TestParseTagFilterErrors(t *testing.T) {
+	for _, expr := range []string{"", "w/", "=primary", "highway=", "highway!="} {
+		_, err := ParseTagFilter(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	assert.True(t, matchWildcard("*", ""))
+	assert.True(t, matchWildcard("a*b*c", "abc"))
+	assert.True(t, matchWildcard("a*b*c", "axxbyyc"))
+	assert.False(t, matchWildcard("a*b*c", "axxbyy"))
+	assert.False(t, matchWildcard("ab*ba", "aba"))
+}
+
+func filterTestFile(t *testing.T) *bytes.Reader {
+	cr := &cachedReader{
+		Nodes: []Node{
+			{Element: Element{ID: 1}}, {Element: Element{ID: 2}}, {Element: Element{ID: 3}},
+			{Element: Element{ID: 4, Tags: map[string]string{"amenity": "bench"}}},
+			{Element: Element{ID: 5}},
+		},
+		Ways: []Way{
+			{Element: Element{ID: 10, Tags: map[string]string{"highway": "primary"}}, NodeIDs: []int64{1, 2}},
+			{Element: Element{ID: 11, Tags: map[string]string{"building": "yes"}}, NodeIDs: []int64{2, 3}},
+			{Element: Element{ID: 12}, NodeIDs: []int64{3, 5}},
+		},
+		Rels: []Relation{
+			{Element: Element{ID: 20}, Members: []RelationMember{{ID: 12, Type: WayType}}},
+			{Element: Element{ID: 21, Tags: map[string]string{"type": "route"}}, Members: []RelationMember{
+				{ID: 22, Type: RelationType}, {ID: 4, Type: NodeType},
+			}},
+			{Element: Element{ID: 22}, Members: []RelationMember{{ID: 20, Type: RelationType}}},
+		},
+	}
+	return bytes.NewReader(encodePBF(t, Header{WritingProgram: "test"}, cr).Bytes())
+}
+
+func elementIDs(cr *cachedReader) (nodes, ways, rels []int64) {
+	for _, n := range cr.Nodes {
+		nodes = append(nodes, n.ID)
+	}
+	for _, w := range cr.Ways {
+		ways = append(ways, w.ID)
+	}
+	for _, r := range cr.Rels {
+		rels = append(rels, r.ID)
+	}
+	return
+}
+
+func TestFilterDecoder(t *testing.T) {
+	f, err := ParseTagFilter("w/highway", "n/amenity")
+	assert.Nil(t, err)
+	cr := &cachedReader{}
+	dec := NewFilterDecoder(filterTestFile(t), f)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(cr))
+
+	nodes, ways, rels := elementIDs(cr)
+	assert.Equal(t, []int64{4}, nodes)
+	assert.Equal(t, []int64{10}, ways)
+	assert.Nil(t, rels)
+}
+
+func TestFilterDecoderComplete(t *testing.T) {
+	f, err := ParseTagFilter("w/highway", "r/type=route")
+	assert.Nil(t, err)
+	cr := &headerCachedReader{}
+	dec := NewFilterDecoder(filterTestFile(t), f)
+	dec.Complete = true
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(cr))
+
+	// relation 21 references 22, which references 20, which references way 12
+	nodes, ways, rels := elementIDs(&cr.cachedReader)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, nodes)
+	assert.Equal(t, []int64{10, 12}, ways)
+	assert.Equal(t, []int64{20, 21, 22}, rels)
+	assert.Equal(t, "test", cr.Header.WritingProgram)
+}
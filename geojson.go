@@ -0,0 +1,191 @@
+package gosmparse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A GeoJSONEncoder writes elements as GeoJSON features: nodes as Points, ways
+// as LineStrings or Polygons and areas assembled from relations as
+// MultiPolygons. Tags are written as properties.
+type GeoJSONEncoder struct {
+	// Seq writes one feature per line (GeoJSONSeq) instead of a single
+	// FeatureCollection.
+	Seq bool
+	// Metadata adds the properties @id, @type and, if Info is available,
+	// @version to every feature.
+	Metadata bool
+	// AreaRules decide whether closed ways are written as Polygons. If nil,
+	// DefaultAreaRules is used.
+	AreaRules *AreaRules
+
+	w       *bufio.Writer
+	buf     bytes.Buffer
+	json    *json.Encoder
+	started bool
+	closed  bool
+	count   int
+	err     error
+}
+
+// NewGeoJSONEncoder returns a new encoder that writes to w. The options need to
+// be set before the first element is written.
+func NewGeoJSONEncoder(w io.Writer) *GeoJSONEncoder {
+	e := &GeoJSONEncoder{w: bufio.NewWriter(w)}
+	e.json = json.NewEncoder(&e.buf)
+	e.json.SetEscapeHTML(false)
+	return e
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// WriteNode writes n as Point feature.
+func (e *GeoJSONEncoder) WriteNode(n Node) error {
+	return e.writeFeature(&n.Element, NodeType, geoJSONGeometry{
+		Type:        "Point",
+		Coordinates: [2]float64{n.Lon, n.Lat},
+	})
+}
+
+// WriteWay writes w as LineString feature, or as Polygon feature if it is an
+// area according to AreaRules. The geometry is taken from w.Coords; ways
+// without coordinates or with missing node locations (see LocationDecoder and
+// Way.CoordsComplete) are skipped.
+func (e *GeoJSONEncoder) WriteWay(w Way) error {
+	if len(w.Coords) < 2 || !w.CoordsComplete() {
+		return e.err
+	}
+	rules := e.AreaRules
+	if rules == nil {
+		rules = DefaultAreaRules()
+		e.AreaRules = rules
+	}
+	if rules.IsArea(w) {
+		ring := Ring(w.Coords)
+		if signedArea(ring) < 0 {
+			ring = reverseRing(ring)
+		}
+		return e.writeFeature(&w.Element, WayType, geoJSONGeometry{
+			Type:        "Polygon",
+			Coordinates: [][][2]float64{ringCoords(ring)},
+		})
+	}
+	return e.writeFeature(&w.Element, WayType, geoJSONGeometry{
+		Type:        "LineString",
+		Coordinates: ringCoords(w.Coords),
+	})
+}
+
+// WriteRelation does nothing, as relations have no geometry by themselves.
+// Use WriteArea for multipolygon relations.
+func (e *GeoJSONEncoder) WriteRelation(r Relation) error {
+	return e.err
+}
+
+// WriteArea writes the relation r with the geometry mp (see AssembleArea) as
+// MultiPolygon feature.
+func (e *GeoJSONEncoder) WriteArea(r Relation, mp MultiPolygon) error {
+	polys := make([][][][2]float64, len(mp))
+	for i, p := range mp {
+		rings := [][][2]float64{ringCoords(p.Outer)}
+		for _, inner := range p.Inners {
+			rings = append(rings, ringCoords(inner))
+		}
+		polys[i] = rings
+	}
+	return e.writeFeature(&r.Element, RelationType, geoJSONGeometry{
+		Type:        "MultiPolygon",
+		Coordinates: polys,
+	})
+}
+
+// Close finishes the output and flushes all buffered data. It does not close
+// the underlying writer.
+func (e *GeoJSONEncoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.start()
+	e.closed = true
+	if !e.Seq {
+		e.write([]byte("\n]}\n"))
+	}
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *GeoJSONEncoder) start() {
+	if e.started {
+		return
+	}
+	e.started = true
+	if !e.Seq {
+		e.write([]byte(`{"type":"FeatureCollection","features":[`))
+	}
+}
+
+func (e *GeoJSONEncoder) writeFeature(el *Element, t MemberType, geom geoJSONGeometry) error {
+	if e.closed {
+		return fmt.Errorf("encoder has already been closed")
+	}
+	e.start()
+	props := make(map[string]interface{}, len(el.Tags)+3)
+	for k, v := range el.Tags {
+		props[k] = v
+	}
+	if e.Metadata {
+		props["@id"] = el.ID
+		props["@type"] = t.String()
+		if el.Info != nil {
+			props["@version"] = el.Info.Version
+		}
+	}
+
+	e.buf.Reset()
+	if err := e.json.Encode(geoJSONFeature{Type: "Feature", Geometry: geom, Properties: props}); err != nil {
+		return err
+	}
+	switch {
+	case e.Seq:
+	case e.count == 0:
+		e.write([]byte("\n"))
+	default:
+		e.write([]byte(",\n"))
+	}
+	out := e.buf.Bytes()
+	if !e.Seq {
+		out = bytes.TrimSuffix(out, []byte("\n"))
+	}
+	e.write(out)
+	e.count++
+	return e.err
+}
+
+func (e *GeoJSONEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func ringCoords(r []LatLon) [][2]float64 {
+	coords := make([][2]float64, len(r))
+	for i, ll := range r {
+		coords[i] = [2]float64{ll.Lon, ll.Lat}
+	}
+	return coords
+}
@@ -0,0 +1,99 @@
+package gosmparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeGeoJSONSample(t *testing.T, enc *GeoJSONEncoder) {
+	assert.Nil(t, enc.WriteNode(Node{
+		Element: Element{ID: 1, Tags: map[string]string{"amenity": "bench"}, Info: &Info{Version: 3}},
+		Lat:     52.5, Lon: 13.4,
+	}))
+	assert.Nil(t, enc.WriteWay(testWay(2, areaCoords, 1, 2, 3)))
+	building := testWay(3, areaCoords, 1, 4, 3, 2, 1)
+	building.Tags = map[string]string{"building": "yes", "name": "<Tom & Jerry>"}
+	assert.Nil(t, enc.WriteWay(building))
+	// no geometry
+	assert.Nil(t, enc.WriteWay(Way{Element: Element{ID: 4}, NodeIDs: []int64{1, 2}}))
+	// missing node location
+	assert.Nil(t, enc.WriteWay(Way{
+		Element: Element{ID: 6},
+		NodeIDs: []int64{1, 2, 3},
+		Coords:  []LatLon{areaCoords[1], MissingLatLon, areaCoords[3]},
+	}))
+	rel := multipolygon(RelationMember{ID: 5, Type: WayType, Role: "outer"})
+	assert.Nil(t, enc.WriteRelation(rel))
+	mp, _ := AssembleArea(rel, map[int64]Way{5: testWay(5, areaCoords, 13, 14, 15, 16, 13)})
+	assert.Nil(t, enc.WriteArea(rel, mp))
+	assert.Nil(t, enc.Close())
+}
+
+type testFeature struct {
+	Type     string
+	Geometry struct {
+		Type        string
+		Coordinates json.RawMessage
+	}
+	Properties map[string]interface{}
+}
+
+func TestGeoJSONFeatureCollection(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewGeoJSONEncoder(&buf)
+	enc.Metadata = true
+	writeGeoJSONSample(t, enc)
+	assert.Contains(t, buf.String(), `"name":"<Tom & Jerry>"`)
+
+	var fc struct {
+		Type     string
+		Features []testFeature
+	}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &fc))
+	assert.Equal(t, "FeatureCollection", fc.Type)
+	assert.Len(t, fc.Features, 4)
+
+	point := fc.Features[0]
+	assert.Equal(t, "Point", point.Geometry.Type)
+	assert.JSONEq(t, `[13.4, 52.5]`, string(point.Geometry.Coordinates))
+	assert.Equal(t, map[string]interface{}{"amenity": "bench", "@id": 1.0, "@type": "node", "@version": 3.0}, point.Properties)
+
+	assert.Equal(t, "LineString", fc.Features[1].Geometry.Type)
+	assert.JSONEq(t, `[[0,0],[10,0],[10,10]]`, string(fc.Features[1].Geometry.Coordinates))
+
+	// clockwise input is written counterclockwise
+	assert.Equal(t, "Polygon", fc.Features[2].Geometry.Type)
+	assert.JSONEq(t, `[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`, string(fc.Features[2].Geometry.Coordinates))
+
+	assert.Equal(t, "MultiPolygon", fc.Features[3].Geometry.Type)
+	assert.Equal(t, "relation", fc.Features[3].Properties["@type"])
+	assert.JSONEq(t, `[[[[20,20],[21,20],[21,21],[20,21],[20,20]]]]`, string(fc.Features[3].Geometry.Coordinates))
+}
+
+func TestGeoJSONSeq(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewGeoJSONEncoder(&buf)
+	enc.Seq = true
+	writeGeoJSONSample(t, enc)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 4)
+	for _, l := range lines {
+		var f testFeature
+		assert.Nil(t, json.Unmarshal([]byte(l), &f))
+		assert.Equal(t, "Feature", f.Type)
+		assert.NotContains(t, f.Properties, "@id")
+	}
+}
+
+func TestGeoJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, NewGeoJSONEncoder(&buf).Close())
+	var fc map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &fc))
+	assert.Equal(t, []interface{}{}, fc["features"])
+}
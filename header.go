@@ -0,0 +1,119 @@
+package gosmparse
+
+import (
+	"math"
+	"time"
+
+	"github.com/thomersch/gosmparse/OSMPBF"
+	"google.golang.org/protobuf/proto"
+)
+
+// Well-known features of the PBF header.
+const (
+	FeatureOsmSchema      = "OsmSchema-V0.6"
+	FeatureDenseNodes     = "DenseNodes"
+	FeatureHistorical     = "HistoricalInformation"
+	FeatureSortTypeThenID = "Sort.Type_then_ID"
+)
+
+// Header contains the information of the header block of a PBF file.
+type Header struct {
+	BoundingBox      *BoundingBox
+	RequiredFeatures []string
+	OptionalFeatures []string
+	WritingProgram   string
+	Source           string
+
+	// Replication fields allow to continue updating the file with change files
+	// from a replication server.
+	ReplicationTimestamp      time.Time
+	ReplicationSequenceNumber int64
+	ReplicationBaseURL        string
+}
+
+// HasFeature reports whether feature is one of the required or optional features.
+func (h *Header) HasFeature(feature string) bool {
+	for _, f := range h.RequiredFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	for _, f := range h.OptionalFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func headerFromPBF(hb *OSMPBF.HeaderBlock) Header {
+	h := Header{
+		RequiredFeatures:          hb.GetRequiredFeatures(),
+		OptionalFeatures:          hb.GetOptionalFeatures(),
+		WritingProgram:            hb.GetWritingprogram(),
+		Source:                    hb.GetSource(),
+		ReplicationSequenceNumber: hb.GetOsmosisReplicationSequenceNumber(),
+		ReplicationBaseURL:        hb.GetOsmosisReplicationBaseUrl(),
+	}
+	if hb.OsmosisReplicationTimestamp != nil {
+		h.ReplicationTimestamp = time.Unix(hb.GetOsmosisReplicationTimestamp(), 0)
+	}
+	if bb := hb.GetBbox(); bb != nil {
+		h.BoundingBox = &BoundingBox{
+			MinLat: 1e-9 * float64(bb.GetBottom()),
+			MinLon: 1e-9 * float64(bb.GetLeft()),
+			MaxLat: 1e-9 * float64(bb.GetTop()),
+			MaxLon: 1e-9 * float64(bb.GetRight()),
+		}
+	}
+	return h
+}
+
+func (h *Header) pbf() *OSMPBF.HeaderBlock {
+	hb := &OSMPBF.HeaderBlock{
+		RequiredFeatures: appendFeatures([]string{FeatureOsmSchema, FeatureDenseNodes}, h.RequiredFeatures...),
+		OptionalFeatures: h.OptionalFeatures,
+	}
+	if h.WritingProgram != "" {
+		hb.Writingprogram = proto.String(h.WritingProgram)
+	}
+	if h.Source != "" {
+		hb.Source = proto.String(h.Source)
+	}
+	if !h.ReplicationTimestamp.IsZero() {
+		hb.OsmosisReplicationTimestamp = proto.Int64(h.ReplicationTimestamp.Unix())
+	}
+	if h.ReplicationSequenceNumber != 0 {
+		hb.OsmosisReplicationSequenceNumber = proto.Int64(h.ReplicationSequenceNumber)
+	}
+	if h.ReplicationBaseURL != "" {
+		hb.OsmosisReplicationBaseUrl = proto.String(h.ReplicationBaseURL)
+	}
+	if bb := h.BoundingBox; bb != nil {
+		hb.Bbox = &OSMPBF.HeaderBBox{
+			Left:   proto.Int64(toNano(bb.MinLon)),
+			Right:  proto.Int64(toNano(bb.MaxLon)),
+			Top:    proto.Int64(toNano(bb.MaxLat)),
+			Bottom: proto.Int64(toNano(bb.MinLat)),
+		}
+	}
+	return hb
+}
+
+// appendFeatures adds all features to list that are not contained yet.
+func appendFeatures(list []string, features ...string) []string {
+outer:
+	for _, f := range features {
+		for _, existing := range list {
+			if existing == f {
+				continue outer
+			}
+		}
+		list = append(list, f)
+	}
+	return list
+}
+
+func toNano(deg float64) int64 {
+	return int64(math.Round(deg * 1e9))
+}
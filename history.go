@@ -0,0 +1,148 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+)
+
+// NodeHistory contains all versions of a node, ordered by version.
+type NodeHistory struct {
+	ID       int64
+	Versions []Node
+}
+
+// WayHistory contains all versions of a way, ordered by version.
+type WayHistory struct {
+	ID       int64
+	Versions []Way
+}
+
+// RelationHistory contains all versions of a relation, ordered by version.
+type RelationHistory struct {
+	ID       int64
+	Versions []Relation
+}
+
+// Changes returns the differences between Versions[i-1] and Versions[i]. As
+// the versions differ, ChangedInfo is always set.
+func (h NodeHistory) Changes(i int) DiffChanges {
+	a, b := entity{Type: NodeType, Node: h.Versions[i-1]}, entity{Type: NodeType, Node: h.Versions[i]}
+	return a.changes(&b)
+}
+
+// Changes returns the differences between Versions[i-1] and Versions[i]. As
+// the versions differ, ChangedInfo is always set.
+func (h WayHistory) Changes(i int) DiffChanges {
+	a, b := entity{Type: WayType, Way: h.Versions[i-1]}, entity{Type: WayType, Way: h.Versions[i]}
+	return a.changes(&b)
+}
+
+// Changes returns the differences between Versions[i-1] and Versions[i]. As
+// the versions differ, ChangedInfo is always set.
+func (h RelationHistory) Changes(i int) DiffChanges {
+	a, b := entity{Type: RelationType, Relation: h.Versions[i-1]}, entity{Type: RelationType, Relation: h.Versions[i]}
+	return a.changes(&b)
+}
+
+// HistoryReader is the interface that needs to be implemented in order to
+// receive elements from HistoryDecoder. If it implements HeaderReader as well,
+// the header is delivered before the elements.
+type HistoryReader interface {
+	ReadNodeHistory(NodeHistory)
+	ReadWayHistory(WayHistory)
+	ReadRelationHistory(RelationHistory)
+}
+
+// A HistoryDecoder reads a PBF history file and delivers all versions of an
+// element at once. The file needs to be sorted by type, ID and version (see
+// Sort); only the versions of one element are held in memory.
+type HistoryDecoder struct {
+	r io.Reader
+}
+
+// NewHistoryDecoder returns a new decoder that reads from r. The Info field of
+// the versions is populated.
+func NewHistoryDecoder(r io.Reader) *HistoryDecoder {
+	return &HistoryDecoder{r: r}
+}
+
+// Parse starts the parsing process that will stream the histories into the
+// given HistoryReader. They are delivered sequentially in file order. Parse
+// fails if the file is not sorted.
+func (d *HistoryDecoder) Parse(o HistoryReader) error {
+	done := make(chan struct{})
+	defer close(done)
+	s := newPBFStream(d.r, done)
+
+	var started bool
+	readHeader := func() {
+		if started {
+			return
+		}
+		started = true
+		if hr, ok := o.(HeaderReader); ok {
+			hr.ReadHeader(s.header)
+		}
+	}
+	err := groupVersions(s.run(0), func(versions []entity) error {
+		readHeader()
+		id := versions[0].element().ID
+		switch versions[0].Type {
+		case NodeType:
+			h := NodeHistory{ID: id, Versions: make([]Node, len(versions))}
+			for i := range versions {
+				h.Versions[i] = versions[i].Node
+			}
+			o.ReadNodeHistory(h)
+		case WayType:
+			h := WayHistory{ID: id, Versions: make([]Way, len(versions))}
+			for i := range versions {
+				h.Versions[i] = versions[i].Way
+			}
+			o.ReadWayHistory(h)
+		default:
+			h := RelationHistory{ID: id, Versions: make([]Relation, len(versions))}
+			for i := range versions {
+				h.Versions[i] = versions[i].Relation
+			}
+			o.ReadRelationHistory(h)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	readHeader()
+	return nil
+}
+
+// groupVersions reads the sorted run in and calls fn with the versions of
+// each element. The versions are only valid during the call. The elements
+// need to have meta data.
+func groupVersions(in *run, fn func(versions []entity) error) error {
+	var versions []entity
+	for {
+		ok, err := in.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		e := &in.head
+		if e.element().Info == nil {
+			return fmt.Errorf("%s %d has no meta data", e.Type, e.element().ID)
+		}
+		if len(versions) > 0 && versions[0].key() != e.key() {
+			if err := fn(versions); err != nil {
+				return err
+			}
+			versions = versions[:0]
+		}
+		versions = append(versions, *e)
+	}
+	if len(versions) > 0 {
+		return fn(versions)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package gosmparse
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type historyCollector struct {
+	Header Header
+	Nodes  []NodeHistory
+	Ways   []WayHistory
+	Rels   []RelationHistory
+}
+
+func (c *historyCollector) ReadHeader(h Header)                   { c.Header = h }
+func (c *historyCollector) ReadNodeHistory(h NodeHistory)         { c.Nodes = append(c.Nodes, h) }
+func (c *historyCollector) ReadWayHistory(h WayHistory)           { c.Ways = append(c.Ways, h) }
+func (c *historyCollector) ReadRelationHistory(h RelationHistory) { c.Rels = append(c.Rels, h) }
+
+func TestHistoryDecoder(t *testing.T) {
+	c := &historyCollector{}
+	assert.Nil(t, NewHistoryDecoder(sortedHistoryFile(t)).Parse(c))
+
+	assert.True(t, c.Header.HasFeature(FeatureHistorical))
+	assert.Len(t, c.Nodes, 2)
+	n1 := c.Nodes[0]
+	assert.Equal(t, int64(1), n1.ID)
+	assert.Len(t, n1.Versions, 2)
+	assert.Equal(t, 1, n1.Versions[0].Info.Version)
+	assert.Equal(t, 2, n1.Versions[1].Info.Version)
+	assert.Equal(t, ChangedLocation|ChangedInfo, n1.Changes(1))
+
+	n2 := c.Nodes[1]
+	assert.Equal(t, int64(2), n2.ID)
+	assert.False(t, n2.Versions[1].Info.Visible)
+
+	assert.Len(t, c.Ways, 2)
+	assert.Equal(t, int64(1), c.Ways[0].ID)
+	assert.Len(t, c.Ways[0].Versions, 1)
+	assert.Equal(t, int64(2), c.Ways[1].ID)
+	assert.Equal(t, "new line", c.Ways[1].Versions[0].Tags["name"])
+
+	assert.Len(t, c.Rels, 2)
+	assert.Equal(t, []RelationMember{{ID: 1, Type: NodeType}, {ID: 1, Type: WayType}}, c.Rels[0].Versions[0].Members)
+}
+
+func TestHistoryChanges(t *testing.T) {
+	info := func(v int) *Info { return &Info{Version: v, Visible: true} }
+	h := WayHistory{ID: 1, Versions: []Way{
+		{Element: Element{ID: 1, Info: info(1), Tags: map[string]string{"a": "b"}}, NodeIDs: []int64{1, 2}},
+		{Element: Element{ID: 1, Info: info(2), Tags: map[string]string{"a": "c"}}, NodeIDs: []int64{1, 2}},
+		{Element: Element{ID: 1, Info: info(3), Tags: map[string]string{"a": "c"}}, NodeIDs: []int64{1, 3}},
+	}}
+	assert.Equal(t, ChangedTags|ChangedInfo, h.Changes(1))
+	assert.Equal(t, ChangedNodes|ChangedInfo, h.Changes(2))
+
+	rh := RelationHistory{ID: 1, Versions: []Relation{
+		{Element: Element{ID: 1, Info: info(1)}},
+		{Element: Element{ID: 1, Info: info(2)}, Members: []RelationMember{{ID: 1, Type: NodeType}}},
+	}}
+	assert.Equal(t, ChangedMembers|ChangedInfo, rh.Changes(1))
+}
+
+func TestHistoryDecoderUnsorted(t *testing.T) {
+	f, err := os.Open("testdata/history.osh.pbf")
+	assert.Nil(t, err)
+	defer f.Close()
+	err = NewHistoryDecoder(f).Parse(&historyCollector{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not sorted")
+}
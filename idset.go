@@ -0,0 +1,405 @@
+package gosmparse
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+const (
+	idContainerBits = 16
+	idContainerSize = 1 << idContainerBits
+	idBitmapWords   = idContainerSize / 64
+	// maxSparseIDs is the number of IDs at which a container switches from
+	// the sparse to the bitmap representation; both then need 8 KiB.
+	maxSparseIDs = 4096
+)
+
+// An IDSet is a set of element IDs, which is suited for billions of IDs. The
+// IDs are split into containers of 65536 consecutive IDs; containers with
+// few IDs store them as sorted list (2 bytes per ID), the others as bitmap
+// (8 KiB). Negative IDs are supported.
+//
+// The zero value is an empty set. An IDSet is not safe for concurrent use.
+type IDSet struct {
+	containers map[int64]*idContainer
+	// keys are the sorted keys of containers; nil if they need to be
+	// sorted again.
+	keys []int64
+	n    int
+}
+
+// NewIDSet returns an empty set.
+func NewIDSet() *IDSet {
+	return &IDSet{}
+}
+
+type idContainer struct {
+	// sparse holds the IDs in ascending order while bitmap is nil.
+	sparse []uint16
+	bitmap *[idBitmapWords]uint64
+	n      int
+}
+
+func splitID(id int64) (int64, uint16) {
+	return id >> idContainerBits, uint16(id & (idContainerSize - 1))
+}
+
+// Add adds id to the set.
+func (s *IDSet) Add(id int64) {
+	key, low := splitID(id)
+	c := s.containers[key]
+	if c == nil {
+		if s.containers == nil {
+			s.containers = make(map[int64]*idContainer)
+		}
+		c = &idContainer{}
+		s.containers[key] = c
+		s.keys = nil
+	}
+	if c.add(low) {
+		s.n++
+	}
+}
+
+// Has reports whether id is in the set.
+func (s *IDSet) Has(id int64) bool {
+	key, low := splitID(id)
+	c := s.containers[key]
+	return c != nil && c.has(low)
+}
+
+// Len returns the number of IDs in the set.
+func (s *IDSet) Len() int {
+	return s.n
+}
+
+// Each calls fn for the IDs in ascending order, until fn returns false. The
+// set must not be modified during the iteration.
+func (s *IDSet) Each(fn func(id int64) bool) {
+	for _, key := range s.sortedKeys() {
+		base := key << idContainerBits
+		if !s.containers[key].each(func(low uint16) bool {
+			return fn(base | int64(low))
+		}) {
+			return
+		}
+	}
+}
+
+// IDs returns all IDs in ascending order.
+func (s *IDSet) IDs() []int64 {
+	ids := make([]int64, 0, s.n)
+	s.Each(func(id int64) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
+
+// Union returns a new set with the IDs that are in s or other.
+func (s *IDSet) Union(other *IDSet) *IDSet {
+	u := &IDSet{}
+	for _, set := range []*IDSet{s, other} {
+		for key, c := range set.containers {
+			uc := u.containers[key]
+			if uc == nil {
+				if u.containers == nil {
+					u.containers = make(map[int64]*idContainer)
+				}
+				u.containers[key] = c.clone()
+				u.n += c.n
+				continue
+			}
+			u.n -= uc.n
+			uc.union(c)
+			u.n += uc.n
+		}
+	}
+	return u
+}
+
+// Intersect returns a new set with the IDs that are in both s and other.
+func (s *IDSet) Intersect(other *IDSet) *IDSet {
+	if other.n < s.n {
+		s, other = other, s
+	}
+	in := &IDSet{}
+	for key, c := range s.containers {
+		oc := other.containers[key]
+		if oc == nil {
+			continue
+		}
+		c.each(func(low uint16) bool {
+			if oc.has(low) {
+				in.Add(key<<idContainerBits | int64(low))
+			}
+			return true
+		})
+	}
+	return in
+}
+
+func (s *IDSet) sortedKeys() []int64 {
+	if s.keys == nil && len(s.containers) > 0 {
+		s.keys = make([]int64, 0, len(s.containers))
+		for key := range s.containers {
+			s.keys = append(s.keys, key)
+		}
+		sort.Slice(s.keys, func(i, j int) bool { return s.keys[i] < s.keys[j] })
+	}
+	return s.keys
+}
+
+var idSetMagic = []byte("GOSMIDS1")
+
+const (
+	idContainerSparse byte = iota
+	idContainerBitmap
+)
+
+// WriteTo writes the set in a binary format to w, which can be read with
+// ReadFrom. It implements io.WriterTo.
+func (s *IDSet) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	cw.write(idSetMagic)
+	cw.writeUvarint(uint64(len(s.containers)))
+	for _, key := range s.sortedKeys() {
+		c := s.containers[key]
+		var buf [binary.MaxVarintLen64]byte
+		cw.write(buf[:binary.PutVarint(buf[:], key)])
+		if c.bitmap != nil {
+			cw.write([]byte{idContainerBitmap})
+			b := make([]byte, 8*idBitmapWords)
+			for i, word := range c.bitmap {
+				binary.LittleEndian.PutUint64(b[8*i:], word)
+			}
+			cw.write(b)
+			continue
+		}
+		cw.write([]byte{idContainerSparse})
+		cw.writeUvarint(uint64(len(c.sparse)))
+		b := make([]byte, 2*len(c.sparse))
+		for i, low := range c.sparse {
+			binary.LittleEndian.PutUint16(b[2*i:], low)
+		}
+		cw.write(b)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.(*bufio.Writer).Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ReadFrom replaces the content of s with a set that has been written with
+// WriteTo. It implements io.ReaderFrom and reads exactly the bytes of the set,
+// so r may continue with other data. r is not buffered; wrapping it in a
+// bufio.Reader speeds up reading large sets.
+func (s *IDSet) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	magic := make([]byte, len(idSetMagic))
+	if _, err := io.ReadFull(cr, magic); err != nil {
+		return cr.n, err
+	}
+	if string(magic) != string(idSetMagic) {
+		return cr.n, fmt.Errorf("not an ID set")
+	}
+	count, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return cr.n, err
+	}
+
+	set := IDSet{containers: make(map[int64]*idContainer)}
+	for i := uint64(0); i < count; i++ {
+		key, err := binary.ReadVarint(cr)
+		if err != nil {
+			return cr.n, err
+		}
+		typ, err := cr.ReadByte()
+		if err != nil {
+			return cr.n, err
+		}
+		c := &idContainer{}
+		switch typ {
+		case idContainerBitmap:
+			b := make([]byte, 8*idBitmapWords)
+			if _, err := io.ReadFull(cr, b); err != nil {
+				return cr.n, err
+			}
+			c.bitmap = new([idBitmapWords]uint64)
+			for j := range c.bitmap {
+				c.bitmap[j] = binary.LittleEndian.Uint64(b[8*j:])
+				c.n += bits.OnesCount64(c.bitmap[j])
+			}
+		case idContainerSparse:
+			n, err := binary.ReadUvarint(cr)
+			if err != nil {
+				return cr.n, err
+			}
+			if n > maxSparseIDs {
+				return cr.n, fmt.Errorf("invalid ID set container with %d entries", n)
+			}
+			b := make([]byte, 2*n)
+			if _, err := io.ReadFull(cr, b); err != nil {
+				return cr.n, err
+			}
+			c.sparse = make([]uint16, n)
+			for j := range c.sparse {
+				c.sparse[j] = binary.LittleEndian.Uint16(b[2*j:])
+				if j > 0 && c.sparse[j] <= c.sparse[j-1] {
+					return cr.n, fmt.Errorf("invalid ID set container: IDs are not sorted")
+				}
+			}
+			c.n = int(n)
+		default:
+			return cr.n, fmt.Errorf("invalid ID set container type %d", typ)
+		}
+		set.containers[key] = c
+		set.n += c.n
+	}
+	*s = set
+	return cr.n, nil
+}
+
+func (c *idContainer) add(low uint16) bool {
+	if c.bitmap != nil {
+		word, mask := low/64, uint64(1)<<(low%64)
+		if c.bitmap[word]&mask != 0 {
+			return false
+		}
+		c.bitmap[word] |= mask
+		c.n++
+		return true
+	}
+	i := sort.Search(len(c.sparse), func(i int) bool { return c.sparse[i] >= low })
+	if i < len(c.sparse) && c.sparse[i] == low {
+		return false
+	}
+	if len(c.sparse) >= maxSparseIDs {
+		c.toBitmap()
+		return c.add(low)
+	}
+	c.sparse = append(c.sparse, 0)
+	copy(c.sparse[i+1:], c.sparse[i:])
+	c.sparse[i] = low
+	c.n++
+	return true
+}
+
+func (c *idContainer) has(low uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[low/64]&(1<<(low%64)) != 0
+	}
+	i := sort.Search(len(c.sparse), func(i int) bool { return c.sparse[i] >= low })
+	return i < len(c.sparse) && c.sparse[i] == low
+}
+
+func (c *idContainer) each(fn func(low uint16) bool) bool {
+	if c.bitmap == nil {
+		for _, low := range c.sparse {
+			if !fn(low) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, word := range c.bitmap {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			if !fn(uint16(i*64 + bit)) {
+				return false
+			}
+			word &^= 1 << uint(bit)
+		}
+	}
+	return true
+}
+
+func (c *idContainer) toBitmap() {
+	c.bitmap = new([idBitmapWords]uint64)
+	for _, low := range c.sparse {
+		c.bitmap[low/64] |= 1 << (low % 64)
+	}
+	c.sparse = nil
+}
+
+func (c *idContainer) clone() *idContainer {
+	cc := &idContainer{n: c.n}
+	if c.bitmap != nil {
+		b := *c.bitmap
+		cc.bitmap = &b
+	} else {
+		cc.sparse = append([]uint16(nil), c.sparse...)
+	}
+	return cc
+}
+
+// union adds all IDs of other to c.
+func (c *idContainer) union(other *idContainer) {
+	if c.bitmap == nil && other.bitmap != nil {
+		c.toBitmap()
+	}
+	if c.bitmap == nil || other.bitmap == nil {
+		other.each(func(low uint16) bool {
+			c.add(low)
+			return true
+		})
+		return
+	}
+	c.n = 0
+	for i := range c.bitmap {
+		c.bitmap[i] |= other.bitmap[i]
+		c.n += bits.OnesCount64(c.bitmap[i])
+	}
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) writeUvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+// countingReader counts the bytes read from r. It does not buffer, so that
+// nothing is read beyond the end of the set.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	buf [1]byte
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) ReadByte() (byte, error) {
+	if br, ok := r.r.(io.ByteReader); ok {
+		b, err := br.ReadByte()
+		if err == nil {
+			r.n++
+		}
+		return b, err
+	}
+	if _, err := io.ReadFull(r, r.buf[:]); err != nil {
+		return 0, err
+	}
+	return r.buf[0], nil
+}
@@ -0,0 +1,148 @@
+package gosmparse
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDSet(t *testing.T) {
+	var s IDSet
+	ids := []int64{0, 1, 63, 64, 65535, 65536, -1, -65536, -65537, 1 << 40}
+	for _, id := range ids {
+		assert.False(t, s.Has(id))
+		s.Add(id)
+	}
+	for _, id := range ids {
+		assert.True(t, s.Has(id), id)
+	}
+	assert.False(t, s.Has(2))
+	assert.False(t, s.Has(-2))
+
+	s.Add(1)
+	assert.Equal(t, len(ids), s.Len())
+
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	assert.Equal(t, sorted, s.IDs())
+}
+
+func TestIDSetBitmap(t *testing.T) {
+	s := NewIDSet()
+	// Enough IDs in one container to switch to the bitmap representation.
+	for id := int64(-100000); id < 0; id += 3 {
+		s.Add(id)
+	}
+	assert.Equal(t, 33334, s.Len())
+	assert.True(t, s.Has(-100000))
+	assert.True(t, s.Has(-70000))
+	assert.False(t, s.Has(-69999))
+	assert.False(t, s.Has(0))
+
+	var (
+		prev  int64 = -100001
+		count int
+	)
+	s.Each(func(id int64) bool {
+		assert.True(t, id > prev, "not sorted: %d after %d", id, prev)
+		prev = id
+		count++
+		return true
+	})
+	assert.Equal(t, s.Len(), count)
+
+	count = 0
+	s.Each(func(id int64) bool {
+		count++
+		return count < 10
+	})
+	assert.Equal(t, 10, count)
+}
+
+func TestIDSetUnionIntersect(t *testing.T) {
+	a, b := NewIDSet(), NewIDSet()
+	for id := int64(0); id < 10000; id++ {
+		a.Add(id * 2)
+		b.Add(id * 3)
+	}
+	a.Add(-5)
+	b.Add(1 << 33)
+
+	u := a.Union(b)
+	assert.Equal(t, 10000+10000-3334+2, u.Len())
+	for _, id := range []int64{-5, 0, 2, 3, 19998, 29997, 1 << 33} {
+		assert.True(t, u.Has(id), id)
+	}
+	assert.False(t, u.Has(1))
+	assert.Equal(t, u.Len(), len(u.IDs()))
+	// The inputs are not modified.
+	assert.Equal(t, 10001, a.Len())
+	assert.False(t, a.Has(3))
+
+	in := a.Intersect(b)
+	assert.Equal(t, 3334, in.Len())
+	assert.True(t, in.Has(6))
+	assert.False(t, in.Has(2))
+	assert.False(t, in.Has(-5))
+	assert.Equal(t, 0, a.Intersect(NewIDSet()).Len())
+}
+
+func TestIDSetSerialize(t *testing.T) {
+	s := NewIDSet()
+	for id := int64(0); id < 20000; id++ {
+		s.Add(id)
+	}
+	for _, id := range []int64{-1 << 40, -3, 1 << 50} {
+		s.Add(id)
+	}
+
+	var buf bytes.Buffer
+	n, err := s.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	var r IDSet
+	n, err = r.ReadFrom(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, s.Len(), r.Len())
+	assert.Equal(t, s.IDs(), r.IDs())
+
+	_, err = r.ReadFrom(bytes.NewReader([]byte("invalid")))
+	assert.NotNil(t, err)
+	_, err = r.ReadFrom(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.NotNil(t, err)
+}
+
+func TestIDSetReadFromStream(t *testing.T) {
+	a, b := NewIDSet(), NewIDSet()
+	for id := int64(0); id < 5000; id++ {
+		a.Add(id)
+		b.Add(id * 100)
+	}
+	var buf bytes.Buffer
+	_, err := a.WriteTo(&buf)
+	assert.Nil(t, err)
+	_, err = b.WriteTo(&buf)
+	assert.Nil(t, err)
+	buf.WriteString("trailer")
+	size := buf.Len()
+
+	// neither buffered nor an io.ByteReader
+	r := io.MultiReader(&buf)
+	var ra, rb IDSet
+	na, err := ra.ReadFrom(r)
+	assert.Nil(t, err)
+	nb, err := rb.ReadFrom(r)
+	assert.Nil(t, err)
+	assert.Equal(t, a.IDs(), ra.IDs())
+	assert.Equal(t, b.IDs(), rb.IDs())
+	rest, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "trailer", string(rest))
+	assert.Equal(t, int64(size-len(rest)), na+nb)
+}
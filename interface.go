@@ -7,3 +7,19 @@ type OSMReader interface {
 	ReadWay(Way)
 	ReadRelation(Relation)
 }
+
+// OSMWriter is the interface implemented by the encoders. Elements are written
+// in the order they are passed in. Close must be called after the last element
+// in order to finish the output; it does not close the underlying writer.
+type OSMWriter interface {
+	WriteNode(Node) error
+	WriteWay(Way) error
+	WriteRelation(Relation) error
+	Close() error
+}
+
+// HeaderReader can optionally be implemented by an OSMReader. If it is, the
+// file header is passed to ReadHeader before any element is read.
+type HeaderReader interface {
+	ReadHeader(Header)
+}
@@ -0,0 +1,339 @@
+package gosmparse
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// LatLon is a coordinate pair in degrees.
+type LatLon struct {
+	Lat, Lon float64
+}
+
+// MissingLatLon marks the location of a node that could not be found, e.g. in
+// Way.Coords. Both coordinates are NaN, so it cannot be mistaken for a real
+// location and does not compare equal to itself; use LatLon.Missing to check
+// for it.
+var MissingLatLon = LatLon{Lat: math.NaN(), Lon: math.NaN()}
+
+// Missing reports whether ll marks an unknown location (see MissingLatLon).
+func (ll LatLon) Missing() bool {
+	return math.IsNaN(ll.Lat) || math.IsNaN(ll.Lon)
+}
+
+// A LocationStore stores node locations by node ID. The implementations in this
+// package store coordinates as fixed-point int32 pairs with a precision of 1e-7
+// degrees (the precision of OSM) and are safe for concurrent use, so they can be
+// populated directly from OSMReader.ReadNode, e.g. with a LocationCollector.
+type LocationStore interface {
+	// Set stores the location of the node with the given ID.
+	Set(id int64, ll LatLon) error
+	// Get returns the location of the node with the given ID. ok is false if
+	// the location is unknown.
+	Get(id int64) (ll LatLon, ok bool, err error)
+}
+
+// location is the fixed-point representation of a LatLon. The latitude is
+// stored with an offset, so that the zero value marks an unset location.
+type location struct {
+	lat, lon int32
+}
+
+const (
+	locationSize = 8
+	latOffset    = 1 << 30
+)
+
+func toLocation(ll LatLon) (location, error) {
+	if ll.Lat < -90 || ll.Lat > 90 || ll.Lon < -180 || ll.Lon > 180 {
+		return location{}, fmt.Errorf("location %v out of range", ll)
+	}
+	return location{
+		lat: int32(math.Round(ll.Lat*1e7)) + latOffset,
+		lon: int32(math.Round(ll.Lon * 1e7)),
+	}, nil
+}
+
+func (l location) valid() bool {
+	return l.lat != 0
+}
+
+func (l location) latLon() LatLon {
+	return LatLon{Lat: float64(l.lat-latOffset) / 1e7, Lon: float64(l.lon) / 1e7}
+}
+
+// MapLocationStore keeps locations in a map. It is suited for small and
+// sparse data sets, e.g. extracts of a city.
+type MapLocationStore struct {
+	mtx  sync.RWMutex
+	locs map[int64]location
+}
+
+// NewMapLocationStore returns an empty MapLocationStore.
+func NewMapLocationStore() *MapLocationStore {
+	return &MapLocationStore{locs: make(map[int64]location)}
+}
+
+// Set implements LocationStore.
+func (s *MapLocationStore) Set(id int64, ll LatLon) error {
+	loc, err := toLocation(ll)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	s.locs[id] = loc
+	s.mtx.Unlock()
+	return nil
+}
+
+// Get implements LocationStore.
+func (s *MapLocationStore) Get(id int64) (LatLon, bool, error) {
+	s.mtx.RLock()
+	loc, ok := s.locs[id]
+	s.mtx.RUnlock()
+	return loc.latLon(), ok, nil
+}
+
+// DenseLocationStore keeps locations in an array indexed by node ID, using 8
+// bytes per ID up to the highest one. It is suited for large extracts that fit
+// into memory. Negative IDs are not supported.
+type DenseLocationStore struct {
+	mtx  sync.RWMutex
+	locs []location
+}
+
+// NewDenseLocationStore returns an empty DenseLocationStore.
+func NewDenseLocationStore() *DenseLocationStore {
+	return &DenseLocationStore{}
+}
+
+// Set implements LocationStore.
+func (s *DenseLocationStore) Set(id int64, ll LatLon) error {
+	if id < 0 {
+		return fmt.Errorf("negative node ID %d is not supported", id)
+	}
+	loc, err := toLocation(ll)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if id >= int64(len(s.locs)) {
+		size := 2 * int64(len(s.locs))
+		if size <= id {
+			size = id + 1
+		}
+		locs := make([]location, size)
+		copy(locs, s.locs)
+		s.locs = locs
+	}
+	s.locs[id] = loc
+	return nil
+}
+
+// Get implements LocationStore.
+func (s *DenseLocationStore) Get(id int64) (LatLon, bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if id < 0 || id >= int64(len(s.locs)) || !s.locs[id].valid() {
+		return LatLon{}, false, nil
+	}
+	return s.locs[id].latLon(), true, nil
+}
+
+const (
+	filePageEntries  = 8192
+	defaultFilePages = 1024
+)
+
+// FileLocationStore keeps locations in a flat file indexed by node ID, using 8
+// bytes per ID up to the highest one. Recently used parts of the file are cached
+// in memory. It is suited for planet-sized data. Negative IDs are not
+// supported.
+type FileLocationStore struct {
+	// MaxPages is the number of cached pages of 64 KiB each.
+	MaxPages int
+
+	mtx   sync.Mutex
+	f     *os.File
+	pages map[int64]*list.Element
+	lru   *list.List
+}
+
+type filePage struct {
+	num   int64
+	locs  [filePageEntries]location
+	dirty bool
+}
+
+// NewFileLocationStore opens or creates the file at path and uses it as
+// storage. Locations that already exist in the file are kept. Close needs to be
+// called to write back all cached changes.
+func NewFileLocationStore(path string) (*FileLocationStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLocationStore{
+		MaxPages: defaultFilePages,
+		f:        f,
+		pages:    make(map[int64]*list.Element),
+		lru:      list.New(),
+	}, nil
+}
+
+// Set implements LocationStore.
+func (s *FileLocationStore) Set(id int64, ll LatLon) error {
+	if id < 0 {
+		return fmt.Errorf("negative node ID %d is not supported", id)
+	}
+	loc, err := toLocation(ll)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, err := s.page(id / filePageEntries)
+	if err != nil {
+		return err
+	}
+	p.locs[id%filePageEntries] = loc
+	p.dirty = true
+	return nil
+}
+
+// Get implements LocationStore.
+func (s *FileLocationStore) Get(id int64) (LatLon, bool, error) {
+	if id < 0 {
+		return LatLon{}, false, nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, err := s.page(id / filePageEntries)
+	if err != nil {
+		return LatLon{}, false, err
+	}
+	loc := p.locs[id%filePageEntries]
+	return loc.latLon(), loc.valid(), nil
+}
+
+// Close writes back all cached changes and closes the file.
+func (s *FileLocationStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for e := s.lru.Front(); e != nil; e = e.Next() {
+		if err := s.writePage(e.Value.(*filePage)); err != nil {
+			s.f.Close()
+			return err
+		}
+	}
+	return s.f.Close()
+}
+
+// page returns the cached page num, loading it from the file if necessary.
+func (s *FileLocationStore) page(num int64) (*filePage, error) {
+	if e, ok := s.pages[num]; ok {
+		s.lru.MoveToFront(e)
+		return e.Value.(*filePage), nil
+	}
+
+	if s.lru.Len() >= s.MaxPages && s.lru.Len() > 0 {
+		oldest := s.lru.Back()
+		p := oldest.Value.(*filePage)
+		if err := s.writePage(p); err != nil {
+			return nil, err
+		}
+		s.lru.Remove(oldest)
+		delete(s.pages, p.num)
+	}
+
+	p := &filePage{num: num}
+	buf := make([]byte, filePageEntries*locationSize)
+	n, err := s.f.ReadAt(buf, num*filePageEntries*locationSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	for i := 0; i < n/locationSize; i++ {
+		p.locs[i].lat = int32(binary.LittleEndian.Uint32(buf[i*locationSize:]))
+		p.locs[i].lon = int32(binary.LittleEndian.Uint32(buf[i*locationSize+4:]))
+	}
+	s.pages[num] = s.lru.PushFront(p)
+	return p, nil
+}
+
+func (s *FileLocationStore) writePage(p *filePage) error {
+	if !p.dirty {
+		return nil
+	}
+	buf := make([]byte, filePageEntries*locationSize)
+	for i, loc := range p.locs {
+		binary.LittleEndian.PutUint32(buf[i*locationSize:], uint32(loc.lat))
+		binary.LittleEndian.PutUint32(buf[i*locationSize+4:], uint32(loc.lon))
+	}
+	if _, err := s.f.WriteAt(buf, p.num*filePageEntries*locationSize); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// A LocationCollector is an OSMReader that stores the locations of all nodes it
+// reads in Store. Ways and relations are ignored, as well as deleted nodes in
+// history files.
+type LocationCollector struct {
+	Store LocationStore
+
+	mtx sync.Mutex
+	err error
+}
+
+// ReadNode stores the location of n.
+func (c *LocationCollector) ReadNode(n Node) {
+	if n.Info != nil && !n.Info.Visible {
+		return
+	}
+	if err := c.Store.Set(n.ID, LatLon{Lat: n.Lat, Lon: n.Lon}); err != nil {
+		c.mtx.Lock()
+		if c.err == nil {
+			c.err = fmt.Errorf("node %d: %v", n.ID, err)
+		}
+		c.mtx.Unlock()
+	}
+}
+
+// ReadWay does nothing.
+func (c *LocationCollector) ReadWay(Way) {}
+
+// ReadRelation does nothing.
+func (c *LocationCollector) ReadRelation(Relation) {}
+
+// Err returns the first error that occurred while storing a location.
+func (c *LocationCollector) Err() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.err
+}
+
+// Locations looks up the locations of all ids (e.g. Way.NodeIDs) in s. The
+// returned slice has the same length as ids; entries of IDs that could not be
+// found are MissingLatLon and their IDs are returned in missing.
+func Locations(s LocationStore, ids []int64) (lls []LatLon, missing []int64, err error) {
+	lls = make([]LatLon, len(ids))
+	for i, id := range ids {
+		ll, ok, err := s.Get(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			missing = append(missing, id)
+			ll = MissingLatLon
+		}
+		lls[i] = ll
+	}
+	return lls, missing, nil
+}
@@ -0,0 +1,98 @@
+package gosmparse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fileStore, err := NewFileLocationStore(filepath.Join(dir, "nodes.bin"))
+	assert.Nil(t, err)
+	fileStore.MaxPages = 1
+	defer fileStore.Close()
+
+	stores := map[string]LocationStore{
+		"map":   NewMapLocationStore(),
+		"dense": NewDenseLocationStore(),
+		"file":  fileStore,
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, s.Set(1, LatLon{Lat: 52.5200066, Lon: 13.404954}))
+			assert.Nil(t, s.Set(3, LatLon{Lat: -90, Lon: -180}))
+			assert.Nil(t, s.Set(100000, LatLon{Lat: 0, Lon: 0}))
+			assert.NotNil(t, s.Set(4, LatLon{Lat: 91, Lon: 0}))
+
+			ll, ok, err := s.Get(1)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+			assert.InDelta(t, 52.5200066, ll.Lat, 1e-9)
+			assert.InDelta(t, 13.404954, ll.Lon, 1e-9)
+
+			ll, ok, _ = s.Get(3)
+			assert.True(t, ok)
+			assert.Equal(t, LatLon{Lat: -90, Lon: -180}, ll)
+
+			_, ok, _ = s.Get(100000)
+			assert.True(t, ok)
+
+			for _, id := range []int64{0, 2, 4, 99999, 1 << 40} {
+				_, ok, err = s.Get(id)
+				assert.Nil(t, err)
+				assert.False(t, ok, id)
+			}
+		})
+	}
+
+	assert.NotNil(t, stores["dense"].Set(-1, LatLon{}))
+	assert.NotNil(t, stores["file"].Set(-1, LatLon{}))
+	assert.Nil(t, stores["map"].Set(-1, LatLon{Lat: 1, Lon: 1}))
+}
+
+func TestFileLocationStorePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nodes.bin")
+
+	s, err := NewFileLocationStore(path)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Set(12345678, LatLon{Lat: 1.5, Lon: -2.5}))
+	assert.Nil(t, s.Close())
+
+	s, err = NewFileLocationStore(path)
+	assert.Nil(t, err)
+	defer s.Close()
+	ll, ok, err := s.Get(12345678)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, LatLon{Lat: 1.5, Lon: -2.5}, ll)
+}
+
+func TestLocationCollector(t *testing.T) {
+	f, err := os.Open("testdata/way_kv.osm.pbf")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	lc := &LocationCollector{Store: NewDenseLocationStore()}
+	assert.Nil(t, NewDecoder(f).Parse(lc))
+	assert.Nil(t, lc.Err())
+
+	lls, missing, err := Locations(lc.Store, []int64{1, 2, 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{5}, missing)
+	assert.Len(t, lls, 3)
+	assert.InDelta(t, 0.001, lls[0].Lat, 1e-9)
+	assert.InDelta(t, 0.002, lls[1].Lon, 1e-9)
+	assert.True(t, lls[2].Missing())
+	assert.False(t, lls[1].Missing())
+	assert.False(t, LatLon{}.Missing())
+}
@@ -0,0 +1,204 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// A MergeConflict describes an element that occurs in two inputs with the same
+// type, ID and version, but with different content.
+type MergeConflict struct {
+	Type    MemberType
+	ID      int64
+	Version int
+	// Inputs are the indexes of the inputs. The element of the first one is
+	// written.
+	Inputs [2]int
+}
+
+// MergeOptions configures Merge.
+type MergeOptions struct {
+	// Conflict is called for every conflict. If it is nil, conflicts are
+	// ignored.
+	Conflict func(MergeConflict)
+}
+
+// Merge reads the sorted PBF files in inputs and writes all their elements as
+// one sorted PBF file to w. Elements that occur in several inputs with the
+// same type, ID and version are written once; all distinct versions are
+// kept. The inputs are streamed, so the memory usage does not depend on their
+// size.
+//
+// The header of the output has the union of the bounding boxes of the inputs,
+// if all of them have one.
+func Merge(w io.Writer, inputs []io.Reader, opts MergeOptions) error {
+	done := make(chan struct{})
+	defer close(done)
+	streams := make([]*pbfStream, len(inputs))
+	runs := make([]*run, len(inputs))
+	for i, r := range inputs {
+		streams[i] = newPBFStream(r, done)
+		runs[i] = streams[i].run(i)
+	}
+
+	enc := NewEncoder(w)
+	var (
+		started bool
+		last    entity
+		lastRun int
+		written bool
+	)
+	err := mergeRuns(runs, func(e *entity, r *run) error {
+		if !started {
+			// All inputs have delivered their header before their first
+			// element.
+			started = true
+			enc.Header = mergeHeaders(streams)
+		}
+		if written && last.key() == e.key() && last.version() == e.version() {
+			if !last.equal(e) && opts.Conflict != nil {
+				opts.Conflict(MergeConflict{
+					Type: e.Type, ID: e.element().ID, Version: e.version(),
+					Inputs: [2]int{lastRun, r.index},
+				})
+			}
+			return nil
+		}
+		last, lastRun, written = *e, r.index, true
+		return e.write(enc)
+	})
+	if err != nil {
+		return err
+	}
+	if !started {
+		enc.Header = mergeHeaders(streams)
+	}
+	return enc.Close()
+}
+
+func mergeHeaders(streams []*pbfStream) Header {
+	h := Header{
+		WritingProgram:   "gosmparse",
+		OptionalFeatures: []string{FeatureSortTypeThenID},
+	}
+	for _, s := range streams {
+		if s.header.HasFeature(FeatureHistorical) {
+			h.RequiredFeatures = []string{FeatureHistorical}
+		}
+	}
+	for i, s := range streams {
+		b := s.header.BoundingBox
+		if b == nil {
+			h.BoundingBox = nil
+			break
+		}
+		if i == 0 {
+			h.BoundingBox = &BoundingBox{MinLat: b.MinLat, MinLon: b.MinLon, MaxLat: b.MaxLat, MaxLon: b.MaxLon}
+			continue
+		}
+		bb := h.BoundingBox
+		bb.MinLat = math.Min(bb.MinLat, b.MinLat)
+		bb.MinLon = math.Min(bb.MinLon, b.MinLon)
+		bb.MaxLat = math.Max(bb.MaxLat, b.MaxLat)
+		bb.MaxLon = math.Max(bb.MaxLon, b.MaxLon)
+	}
+	return h
+}
+
+// pbfStream decodes a PBF file in the background and delivers its elements in
+// file order through a channel. Closing done stops the decoding: no further
+// elements are delivered and the input is no longer read.
+type pbfStream struct {
+	entities chan entity
+	done     <-chan struct{}
+	header   Header
+
+	mtx sync.Mutex
+	err error
+}
+
+func newPBFStream(r io.Reader, done <-chan struct{}) *pbfStream {
+	s := &pbfStream{entities: make(chan entity, 1000), done: done}
+	go func() {
+		dec := NewDecoderWithInfo(&stoppableReader{r: r, done: done})
+		dec.Workers = 1
+		// Only few blocks are read ahead, as the elements are buffered in
+		// entities anyway and queued blocks are still decoded after done
+		// has been closed.
+		dec.QueueSize = 4
+		err := dec.Parse(s)
+		s.mtx.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mtx.Unlock()
+		close(s.entities)
+	}()
+	return s
+}
+
+// run returns a run that reads the elements of the stream. It fails if the
+// stream is not sorted.
+func (s *pbfStream) run(index int) *run {
+	var (
+		prev    entity
+		hasPrev bool
+	)
+	return &run{index: index, read: func() (entity, bool, error) {
+		e, ok := <-s.entities
+		if !ok {
+			s.mtx.Lock()
+			defer s.mtx.Unlock()
+			return e, false, s.err
+		}
+		if hasPrev && e.less(&prev) {
+			return e, false, fmt.Errorf("input %d is not sorted: %s %d after %s %d",
+				index, e.Type, e.element().ID, prev.Type, prev.element().ID)
+		}
+		prev, hasPrev = e, true
+		return e, true, nil
+	}}
+}
+
+func (s *pbfStream) ReadHeader(h Header) {
+	s.header = h
+}
+
+func (s *pbfStream) ReadNode(n Node) {
+	s.send(entity{Type: NodeType, Node: n})
+}
+
+func (s *pbfStream) ReadWay(w Way) {
+	s.send(entity{Type: WayType, Way: w})
+}
+
+func (s *pbfStream) ReadRelation(r Relation) {
+	s.send(entity{Type: RelationType, Relation: r})
+}
+
+func (s *pbfStream) send(e entity) {
+	select {
+	case s.entities <- e:
+	case <-s.done:
+	}
+}
+
+var errStreamStopped = fmt.Errorf("stream has been stopped")
+
+// stoppableReader fails with errStreamStopped once done is closed, which
+// makes the decoder of a pbfStream return.
+type stoppableReader struct {
+	r    io.Reader
+	done <-chan struct{}
+}
+
+func (r *stoppableReader) Read(b []byte) (int, error) {
+	select {
+	case <-r.done:
+		return 0, errStreamStopped
+	default:
+		return r.r.Read(b)
+	}
+}
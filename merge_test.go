@@ -0,0 +1,110 @@
+package gosmparse
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	info := func(v int) *Info {
+		return &Info{Version: v, Timestamp: time.Unix(int64(v)*1000, 0), Visible: true}
+	}
+	a := encodePBF(t, Header{BoundingBox: &BoundingBox{MinLat: 0, MinLon: 0, MaxLat: 1, MaxLon: 1}}, &cachedReader{
+		Nodes: []Node{
+			{Element: Element{ID: 1, Info: info(1)}, Lat: 0.5, Lon: 0.5},
+			{Element: Element{ID: 2, Info: info(1)}, Lat: 0.9, Lon: 0.9},
+			{Element: Element{ID: 4, Info: info(1)}, Lat: 0.1, Lon: 0.1},
+		},
+		Ways: []Way{
+			{Element: Element{ID: 1, Info: info(1), Tags: map[string]string{"highway": "primary"}}, NodeIDs: []int64{1, 2}},
+		},
+	})
+	b := encodePBF(t, Header{BoundingBox: &BoundingBox{MinLat: 0.8, MinLon: 0.8, MaxLat: 2, MaxLon: 2}}, &cachedReader{
+		Nodes: []Node{
+			// same as in a
+			{Element: Element{ID: 2, Info: info(1)}, Lat: 0.9, Lon: 0.9},
+			{Element: Element{ID: 3, Info: info(1)}, Lat: 1.5, Lon: 1.5},
+			// conflicting location
+			{Element: Element{ID: 4, Info: info(1)}, Lat: 0.2, Lon: 0.2},
+		},
+		Ways: []Way{
+			// newer version
+			{Element: Element{ID: 1, Info: info(2), Tags: map[string]string{"highway": "secondary"}}, NodeIDs: []int64{1, 2}},
+			{Element: Element{ID: 2, Info: info(1)}, NodeIDs: []int64{2, 3}},
+		},
+	})
+	empty := encodePBF(t, Header{}, &cachedReader{})
+
+	var conflicts []MergeConflict
+	var out bytes.Buffer
+	err := Merge(&out, []io.Reader{a, b}, MergeOptions{Conflict: func(c MergeConflict) {
+		conflicts = append(conflicts, c)
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, []MergeConflict{{Type: NodeType, ID: 4, Version: 1, Inputs: [2]int{0, 1}}}, conflicts)
+
+	cr := &headerCachedReader{}
+	dec := NewDecoderWithInfo(&out)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(cr))
+	nodes, ways, rels := elementIDs(&cr.cachedReader)
+	assert.Equal(t, []int64{1, 2, 3, 4}, nodes)
+	assert.Equal(t, 0.1, cr.Nodes[3].Lat)
+	assert.Equal(t, []int64{1, 1, 2}, ways)
+	assert.Nil(t, rels)
+	assert.Equal(t, &BoundingBox{MinLat: 0, MinLon: 0, MaxLat: 2, MaxLon: 2}, cr.Header.BoundingBox)
+	assert.True(t, cr.Header.HasFeature(FeatureSortTypeThenID))
+
+	// the bounding box is dropped if an input has none
+	out.Reset()
+	a2 := encodePBF(t, Header{BoundingBox: &BoundingBox{MaxLat: 1, MaxLon: 1}}, &cachedReader{})
+	assert.Nil(t, Merge(&out, []io.Reader{a2, empty}, MergeOptions{}))
+	cr = &headerCachedReader{}
+	assert.Nil(t, NewDecoder(&out).Parse(cr))
+	assert.Nil(t, cr.Header.BoundingBox)
+}
+
+func TestMergeUnsorted(t *testing.T) {
+	var out bytes.Buffer
+	err := Merge(&out, []io.Reader{unsortedTestFile(t)}, MergeOptions{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "input 0 is not sorted")
+}
+
+// countingTestReader counts the bytes read from r.
+type countingTestReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingTestReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += n
+	return n, err
+}
+
+func TestPBFStreamStop(t *testing.T) {
+	cr := &cachedReader{}
+	// more elements than the stream buffers
+	for id := int64(1); id <= 3000; id++ {
+		cr.Nodes = append(cr.Nodes, Node{Element: Element{ID: id}})
+	}
+	buf := encodePBF(t, Header{}, cr)
+	size := buf.Len()
+	r := &countingTestReader{r: buf}
+
+	done := make(chan struct{})
+	s := newPBFStream(r, done)
+	_, ok := <-s.entities
+	assert.True(t, ok)
+	close(done)
+	for range s.entities {
+	}
+	// the decoder returns without reading the rest of the input
+	assert.True(t, r.n < size, "read %d of %d bytes", r.n, size)
+	assert.Equal(t, errStreamStopped, s.err)
+}
@@ -0,0 +1,356 @@
+package gosmparse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// o5m dataset types
+const (
+	o5mNode      = 0x10
+	o5mWay       = 0x11
+	o5mRelation  = 0x12
+	o5mBBox      = 0xdb
+	o5mTimestamp = 0xdc
+	o5mHeader    = 0xe0
+	o5mEOF       = 0xfe
+	o5mReset     = 0xff
+)
+
+const (
+	// o5mTableSize is the number of entries in the string reference table.
+	o5mTableSize = 15000
+	// o5mMaxStoredLen is the maximum length of strings (or the sum of both
+	// strings of a pair) that are stored in the reference table.
+	o5mMaxStoredLen = 250
+)
+
+// An O5MDecoder reads and decodes o5m data from an input stream.
+type O5MDecoder struct {
+	r        io.Reader
+	withInfo bool
+}
+
+// NewO5MDecoder returns a new decoder that reads o5m from r.
+func NewO5MDecoder(r io.Reader) *O5MDecoder {
+	return &O5MDecoder{r: r}
+}
+
+// NewO5MDecoderWithInfo returns a new decoder similar to NewO5MDecoder, but will
+// populate the Info field in the elements. Use this if you need meta data.
+func NewO5MDecoderWithInfo(r io.Reader) *O5MDecoder {
+	return &O5MDecoder{r: r, withInfo: true}
+}
+
+// o5mState contains the delta coding state and the string reference table,
+// which are both cleared by a reset dataset.
+type o5mState struct {
+	id, timestamp, changeset int64
+	lat, lon                 int64
+	wayRef                   int64
+	memberRef                [3]int64
+
+	table    [o5mTableSize]string
+	tablePos int
+}
+
+func (s *o5mState) reset() {
+	*s = o5mState{}
+}
+
+func (s *o5mState) store(str string) {
+	s.table[s.tablePos] = str
+	s.tablePos = (s.tablePos + 1) % o5mTableSize
+}
+
+func (s *o5mState) lookup(ref uint64) (string, error) {
+	if ref == 0 || ref > o5mTableSize {
+		return "", fmt.Errorf("invalid o5m string reference %d", ref)
+	}
+	return s.table[(s.tablePos-int(ref)+o5mTableSize)%o5mTableSize], nil
+}
+
+// Parse starts the parsing process that will stream data into the given OSMReader.
+// Elements are delivered sequentially in file order. If o implements
+// HeaderReader, the bounding box and the timestamp of the file are passed to
+// it before the first element.
+func (d *O5MDecoder) Parse(o OSMReader) error {
+	var (
+		br         = bufio.NewReader(d.r)
+		st         = &o5mState{}
+		header     Header
+		headerDone bool
+		buf        []byte
+	)
+	sendHeader := func() {
+		if headerDone {
+			return
+		}
+		headerDone = true
+		if hr, ok := o.(HeaderReader); ok {
+			hr.ReadHeader(header)
+		}
+	}
+
+	for {
+		typ, err := br.ReadByte()
+		if err == io.EOF {
+			sendHeader()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case typ == o5mReset:
+			st.reset()
+			continue
+		case typ == o5mEOF:
+			sendHeader()
+			return nil
+		case typ >= 0xf0:
+			continue
+		}
+
+		length, err := binary.ReadUvarint(br)
+		if err != nil {
+			return err
+		}
+		if uint64(cap(buf)) < length {
+			buf = make([]byte, length)
+		}
+		buf = buf[:length]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		p := &o5mParser{buf: buf, st: st}
+
+		switch typ {
+		case o5mHeader:
+			if s := string(buf); s != "o5m2" && s != "o5c2" {
+				return fmt.Errorf("unsupported o5m header %q", s)
+			}
+		case o5mBBox:
+			var c [4]int64
+			for i := range c {
+				c[i] = p.signed()
+			}
+			header.BoundingBox = &BoundingBox{
+				MinLon: float64(c[0]) / 1e7, MinLat: float64(c[1]) / 1e7,
+				MaxLon: float64(c[2]) / 1e7, MaxLat: float64(c[3]) / 1e7,
+			}
+		case o5mTimestamp:
+			header.ReplicationTimestamp = time.Unix(p.signed(), 0)
+		case o5mNode, o5mWay, o5mRelation:
+			sendHeader()
+			if err := p.element(o, typ, d.withInfo); err != nil {
+				return err
+			}
+		}
+		if p.err != nil {
+			return p.err
+		}
+	}
+}
+
+// o5mParser reads the content of a single dataset.
+type o5mParser struct {
+	buf []byte
+	pos int
+	st  *o5mState
+	err error
+}
+
+func (p *o5mParser) done() bool {
+	return p.err != nil || p.pos >= len(p.buf)
+}
+
+func (p *o5mParser) unsigned() uint64 {
+	if p.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(p.buf[p.pos:])
+	if n <= 0 {
+		p.err = fmt.Errorf("invalid o5m number at offset %d", p.pos)
+		return 0
+	}
+	p.pos += n
+	return v
+}
+
+func (p *o5mParser) signed() int64 {
+	if p.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(p.buf[p.pos:])
+	if n <= 0 {
+		p.err = fmt.Errorf("invalid o5m number at offset %d", p.pos)
+		return 0
+	}
+	p.pos += n
+	return v
+}
+
+// cstring reads a zero terminated string.
+func (p *o5mParser) cstring() string {
+	end := bytes.IndexByte(p.buf[p.pos:], 0)
+	if end < 0 {
+		p.err = fmt.Errorf("unterminated o5m string at offset %d", p.pos)
+		return ""
+	}
+	s := string(p.buf[p.pos : p.pos+end])
+	p.pos += end + 1
+	return s
+}
+
+// stringPair reads a string pair, either inline or as table reference.
+func (p *o5mParser) stringPair() (string, string) {
+	if p.err != nil || p.pos >= len(p.buf) {
+		p.err = fmt.Errorf("unexpected end of o5m dataset")
+		return "", ""
+	}
+	if p.buf[p.pos] == 0 {
+		p.pos++
+		k := p.cstring()
+		v := p.cstring()
+		if p.err == nil && len(k)+len(v) <= o5mMaxStoredLen {
+			p.st.store(k + "\x00" + v)
+		}
+		return k, v
+	}
+	s, err := p.st.lookup(p.unsigned())
+	if err != nil {
+		p.err = err
+		return "", ""
+	}
+	i := strings.IndexByte(s, 0)
+	if i < 0 {
+		p.err = fmt.Errorf("o5m string reference does not point to a string pair")
+		return "", ""
+	}
+	return s[:i], s[i+1:]
+}
+
+// singleString reads a single string, either inline or as table reference.
+func (p *o5mParser) singleString() string {
+	if p.err != nil || p.pos >= len(p.buf) {
+		p.err = fmt.Errorf("unexpected end of o5m dataset")
+		return ""
+	}
+	if p.buf[p.pos] == 0 {
+		p.pos++
+		s := p.cstring()
+		if p.err == nil && len(s) <= o5mMaxStoredLen {
+			p.st.store(s)
+		}
+		return s
+	}
+	s, err := p.st.lookup(p.unsigned())
+	if err != nil {
+		p.err = err
+	}
+	return s
+}
+
+func (p *o5mParser) element(o OSMReader, typ byte, withInfo bool) error {
+	st := p.st
+	st.id += p.signed()
+	e := Element{ID: st.id}
+	info := p.info()
+
+	visible := !p.done()
+	if withInfo {
+		info.Visible = visible
+		e.Info = &info
+	}
+
+	switch typ {
+	case o5mNode:
+		n := Node{Element: e}
+		if visible {
+			st.lon += p.signed()
+			st.lat += p.signed()
+			n.Lat, n.Lon = float64(st.lat)/1e7, float64(st.lon)/1e7
+		}
+		n.Tags = p.tags()
+		if p.err != nil {
+			return p.err
+		}
+		o.ReadNode(n)
+	case o5mWay:
+		w := Way{Element: e, NodeIDs: []int64{}}
+		if visible {
+			refsLen := int(p.unsigned())
+			end := p.pos + refsLen
+			for p.err == nil && p.pos < end {
+				st.wayRef += p.signed()
+				w.NodeIDs = append(w.NodeIDs, st.wayRef)
+			}
+		}
+		w.Tags = p.tags()
+		if p.err != nil {
+			return p.err
+		}
+		o.ReadWay(w)
+	case o5mRelation:
+		r := Relation{Element: e, Members: []RelationMember{}}
+		if visible {
+			refsLen := int(p.unsigned())
+			end := p.pos + refsLen
+			for p.err == nil && p.pos < end {
+				delta := p.signed()
+				s := p.singleString()
+				if p.err != nil {
+					break
+				}
+				if len(s) == 0 || s[0] < '0' || s[0] > '2' {
+					return fmt.Errorf("relation %d: invalid o5m member type", e.ID)
+				}
+				mt := MemberType(s[0] - '0')
+				st.memberRef[mt] += delta
+				r.Members = append(r.Members, RelationMember{ID: st.memberRef[mt], Type: mt, Role: s[1:]})
+			}
+		}
+		r.Tags = p.tags()
+		if p.err != nil {
+			return p.err
+		}
+		o.ReadRelation(r)
+	}
+	return nil
+}
+
+func (p *o5mParser) info() Info {
+	var info Info
+	info.Version = int(p.unsigned())
+	if info.Version == 0 {
+		return info
+	}
+	st := p.st
+	st.timestamp += p.signed()
+	if st.timestamp == 0 {
+		return info
+	}
+	info.Timestamp = time.Unix(st.timestamp, 0)
+	st.changeset += p.signed()
+	info.Changeset = st.changeset
+	uid, user := p.stringPair()
+	uidVal, _ := binary.Uvarint([]byte(uid))
+	info.UID = int(uidVal)
+	info.User = user
+	return info
+}
+
+func (p *o5mParser) tags() map[string]string {
+	tags := make(map[string]string)
+	for !p.done() {
+		k, v := p.stringPair()
+		tags[k] = v
+	}
+	return tags
+}
@@ -0,0 +1,140 @@
+package gosmparse
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeO5M(t *testing.T, cr *cachedReader, enc *O5MEncoder) {
+	for _, n := range cr.Nodes {
+		assert.Nil(t, enc.WriteNode(n))
+	}
+	for _, w := range cr.Ways {
+		assert.Nil(t, enc.WriteWay(w))
+	}
+	for _, r := range cr.Rels {
+		assert.Nil(t, enc.WriteRelation(r))
+	}
+	assert.Nil(t, enc.Close())
+}
+
+func TestO5MRoundTrip(t *testing.T) {
+	for _, fn := range []string{"testdata/history.osh", "testdata/relation_kv.osm", "testdata/stringtable.osm"} {
+		t.Run(fn, func(t *testing.T) {
+			orig := readFile(t, fn, parseXMLWithInfo)
+
+			var buf bytes.Buffer
+			enc := NewO5MEncoder(&buf)
+			enc.Bounds = &BoundingBox{MinLat: -1, MinLon: -2, MaxLat: 3, MaxLon: 4}
+			enc.Timestamp = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			encodeO5M(t, orig, enc)
+
+			decoded := &headerCachedReader{}
+			assert.Nil(t, NewO5MDecoderWithInfo(&buf).Parse(decoded))
+			normalize(&decoded.cachedReader)
+			assert.Equal(t, orig.Nodes, decoded.Nodes)
+			assert.Equal(t, orig.Ways, decoded.Ways)
+			assert.Equal(t, orig.Rels, decoded.Rels)
+			assert.Equal(t, enc.Bounds, decoded.Header.BoundingBox)
+			assert.True(t, enc.Timestamp.Equal(decoded.Header.ReplicationTimestamp))
+		})
+	}
+}
+
+// testdata/golden.o5m has been assembled byte by byte following the o5m
+// specification and the output of osmconvert: it starts with a reset, has a
+// reset before the ways and the relations, contains a jump dataset, references
+// earlier strings and repeats a string pair of more than 250 bytes inline, as
+// it is not stored in the string table.
+func TestO5MGolden(t *testing.T) {
+	o5m := &headerCachedReader{}
+	o5mFile, err := os.Open("testdata/golden.o5m")
+	assert.Nil(t, err)
+	defer o5mFile.Close()
+	assert.Nil(t, NewO5MDecoderWithInfo(o5mFile).Parse(o5m))
+	normalize(&o5m.cachedReader)
+
+	assert.Equal(t, &BoundingBox{MinLat: 51.4, MinLon: -0.1, MaxLat: 51.6, MaxLon: 0.1}, o5m.Header.BoundingBox)
+	assert.True(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Equal(o5m.Header.ReplicationTimestamp))
+
+	assert.Len(t, o5m.Nodes, 3)
+	assert.Equal(t, map[string]string{"highway": "bus_stop", "name": "Stop B"}, o5m.Nodes[1].Tags)
+	assert.Equal(t, 42, o5m.Nodes[1].Info.UID)
+	assert.Equal(t, "alice", o5m.Nodes[1].Info.User)
+	assert.False(t, o5m.Nodes[2].Info.Visible)
+	assert.Len(t, o5m.Ways, 2)
+	assert.Equal(t, []int64{2, 1}, o5m.Ways[1].NodeIDs)
+	assert.Equal(t, "residential", o5m.Ways[1].Tags["highway"])
+	assert.Len(t, o5m.Ways[1].Tags["note"], 260)
+	assert.Len(t, o5m.Rels, 1)
+	assert.Equal(t, []RelationMember{
+		{ID: 1, Type: NodeType, Role: "platform"},
+		{ID: 10, Type: WayType},
+		{ID: 2, Type: NodeType, Role: "platform"},
+	}, o5m.Rels[0].Members)
+
+	opl := readFile(t, "testdata/golden.opl", func(f *os.File, o OSMReader) error {
+		return NewOPLDecoderWithInfo(f).Parse(o)
+	})
+	assert.Equal(t, opl.Nodes, o5m.Nodes)
+	assert.Equal(t, opl.Ways, o5m.Ways)
+	assert.Equal(t, opl.Rels, o5m.Rels)
+}
+
+func TestO5MStringTable(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewO5MEncoder(&buf)
+	long := strings.Repeat("x", 300)
+	tags := map[string]string{"highway": "primary", "note": long}
+	for i := int64(1); i <= 3; i++ {
+		assert.Nil(t, enc.WriteWay(Way{Element: Element{ID: i, Tags: tags}, NodeIDs: []int64{i, i + 1}}))
+	}
+	assert.Nil(t, enc.Close())
+	// the short pair is stored once and referenced afterwards, the long one
+	// is repeated every time
+	assert.Equal(t, 1, strings.Count(buf.String(), "highway"))
+	assert.Equal(t, 3, strings.Count(buf.String(), long))
+
+	decoded := &cachedReader{}
+	assert.Nil(t, NewO5MDecoder(&buf).Parse(decoded))
+	assert.Len(t, decoded.Ways, 3)
+	for _, w := range decoded.Ways {
+		assert.Equal(t, tags, w.Tags)
+		assert.Nil(t, w.Info)
+	}
+	assert.Equal(t, []int64{3, 4}, decoded.Ways[2].NodeIDs)
+}
+
+func TestO5MFromPBF(t *testing.T) {
+	f, err := os.Open("testdata/way_kv.osm.pbf")
+	assert.Nil(t, err)
+	defer f.Close()
+	orig := &cachedReader{}
+	dec := NewDecoder(f)
+	dec.Workers = 1
+	assert.Nil(t, dec.Parse(orig))
+	normalize(orig)
+
+	var buf bytes.Buffer
+	encodeO5M(t, orig, NewO5MEncoder(&buf))
+	decoded := &cachedReader{}
+	assert.Nil(t, NewO5MDecoder(&buf).Parse(decoded))
+	normalize(decoded)
+	assert.Equal(t, orig.Nodes, decoded.Nodes)
+	assert.Equal(t, orig.Ways, decoded.Ways)
+}
+
+func TestO5MCorrupt(t *testing.T) {
+	for _, buf := range [][]byte{
+		{0xff, 0xe0, 0x04, 'o', '5', 'x', '2'},
+		{0xff, 0x10, 0x05, 0x02},
+		{0xff, 0x10, 0x03, 0x02, 0x00, 0x01},
+	} {
+		assert.NotNil(t, NewO5MDecoder(bytes.NewReader(buf)).Parse(newMockOSMReader()))
+	}
+}
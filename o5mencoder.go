@@ -0,0 +1,282 @@
+package gosmparse
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// An O5MEncoder writes elements as o5m to an output stream.
+type O5MEncoder struct {
+	// Bounds is written as bounding box dataset, if set.
+	Bounds *BoundingBox
+	// Timestamp is written as file timestamp dataset, if set.
+	Timestamp time.Time
+
+	w       *bufio.Writer
+	started bool
+	closed  bool
+	kind    MemberType
+	err     error
+
+	// delta coding state, cleared on every reset
+	id, timestamp, changeset int64
+	lat, lon                 int64
+	wayRef                   int64
+	memberRef                [3]int64
+	// strings maps the stored strings to their position in the reference
+	// table, counted from the beginning.
+	strings  map[string]int
+	tablePos int
+
+	buf, refs []byte
+}
+
+// NewO5MEncoder returns a new encoder that writes to w. Bounds and Timestamp
+// need to be set before the first element is written.
+func NewO5MEncoder(w io.Writer) *O5MEncoder {
+	return &O5MEncoder{
+		w:       bufio.NewWriter(w),
+		strings: make(map[string]int),
+	}
+}
+
+// WriteNode writes a node dataset.
+func (e *O5MEncoder) WriteNode(n Node) error {
+	if err := e.prepare(NodeType); err != nil {
+		return err
+	}
+	e.writeHead(n.ID, n.Info)
+	if n.Info == nil || n.Info.Visible {
+		lat, lon := int64(math.Round(n.Lat*1e7)), int64(math.Round(n.Lon*1e7))
+		e.buf = appendSigned(e.buf, lon-e.lon)
+		e.buf = appendSigned(e.buf, lat-e.lat)
+		e.lat, e.lon = lat, lon
+		e.writeTags(n.Tags)
+	}
+	return e.writeDataset(o5mNode)
+}
+
+// WriteWay writes a way dataset.
+func (e *O5MEncoder) WriteWay(w Way) error {
+	if err := e.prepare(WayType); err != nil {
+		return err
+	}
+	e.writeHead(w.ID, w.Info)
+	if w.Info == nil || w.Info.Visible {
+		e.refs = e.refs[:0]
+		for _, id := range w.NodeIDs {
+			e.refs = appendSigned(e.refs, id-e.wayRef)
+			e.wayRef = id
+		}
+		e.buf = appendUnsigned(e.buf, uint64(len(e.refs)))
+		e.buf = append(e.buf, e.refs...)
+		e.writeTags(w.Tags)
+	}
+	return e.writeDataset(o5mWay)
+}
+
+// WriteRelation writes a relation dataset.
+func (e *O5MEncoder) WriteRelation(r Relation) error {
+	if err := e.prepare(RelationType); err != nil {
+		return err
+	}
+	e.writeHead(r.ID, r.Info)
+	if r.Info == nil || r.Info.Visible {
+		e.refs = e.refs[:0]
+		for _, m := range r.Members {
+			if m.Type < NodeType || m.Type > RelationType {
+				return fmt.Errorf("relation %d: invalid member type %v", r.ID, m.Type)
+			}
+			e.refs = appendSigned(e.refs, m.ID-e.memberRef[m.Type])
+			e.memberRef[m.Type] = m.ID
+			e.refs = e.appendString(e.refs, string('0'+byte(m.Type))+m.Role)
+		}
+		e.buf = appendUnsigned(e.buf, uint64(len(e.refs)))
+		e.buf = append(e.buf, e.refs...)
+		e.writeTags(r.Tags)
+	}
+	return e.writeDataset(o5mRelation)
+}
+
+// Close writes the end of file marker and flushes all buffered data. It does
+// not close the underlying writer.
+func (e *O5MEncoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.closed = true
+	e.write([]byte{o5mEOF})
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *O5MEncoder) start() error {
+	if e.started {
+		return e.err
+	}
+	e.started = true
+	e.write([]byte{o5mReset})
+	e.buf = append(e.buf[:0], "o5m2"...)
+	e.writeDataset(o5mHeader)
+	if !e.Timestamp.IsZero() {
+		e.buf = appendSigned(e.buf[:0], e.Timestamp.Unix())
+		e.writeDataset(o5mTimestamp)
+	}
+	if b := e.Bounds; b != nil {
+		e.buf = e.buf[:0]
+		for _, c := range []float64{b.MinLon, b.MinLat, b.MaxLon, b.MaxLat} {
+			e.buf = appendSigned(e.buf, int64(math.Round(c*1e7)))
+		}
+		e.writeDataset(o5mBBox)
+	}
+	return e.err
+}
+
+// prepare starts the file and resets the state whenever the element type
+// changes, as it is done by other o5m writers.
+func (e *O5MEncoder) prepare(kind MemberType) error {
+	if e.closed {
+		return fmt.Errorf("encoder has already been closed")
+	}
+	if err := e.start(); err != nil {
+		return err
+	}
+	if kind != e.kind {
+		e.write([]byte{o5mReset})
+		e.reset()
+		e.kind = kind
+	}
+	e.buf = e.buf[:0]
+	return e.err
+}
+
+func (e *O5MEncoder) reset() {
+	e.id, e.timestamp, e.changeset = 0, 0, 0
+	e.lat, e.lon, e.wayRef = 0, 0, 0
+	e.memberRef = [3]int64{}
+	e.strings = make(map[string]int)
+	e.tablePos = 0
+}
+
+func (e *O5MEncoder) writeHead(id int64, info *Info) {
+	e.buf = appendSigned(e.buf, id-e.id)
+	e.id = id
+	if info == nil {
+		e.buf = append(e.buf, 0)
+		return
+	}
+	e.buf = appendUnsigned(e.buf, uint64(info.Version))
+	if info.Version == 0 {
+		return
+	}
+	ts := info.Timestamp.Unix()
+	if info.Timestamp.IsZero() {
+		ts = 0
+	}
+	e.buf = appendSigned(e.buf, ts-e.timestamp)
+	e.timestamp = ts
+	if ts == 0 {
+		return
+	}
+	e.buf = appendSigned(e.buf, info.Changeset-e.changeset)
+	e.changeset = info.Changeset
+	var uid []byte
+	if info.UID != 0 {
+		uid = appendUnsigned(nil, uint64(info.UID))
+	}
+	e.buf = e.appendPair(e.buf, string(uid), info.User)
+}
+
+func (e *O5MEncoder) writeTags(tags map[string]string) {
+	for _, k := range sortedKeys(tags) {
+		e.buf = e.appendPair(e.buf, k, tags[k])
+	}
+}
+
+func (e *O5MEncoder) appendPair(buf []byte, k, v string) []byte {
+	if ref, ok := e.reference(k + "\x00" + v); ok {
+		return appendUnsigned(buf, ref)
+	}
+	buf = append(buf, 0)
+	buf = append(buf, k...)
+	buf = append(buf, 0)
+	buf = append(buf, v...)
+	buf = append(buf, 0)
+	if len(k)+len(v) <= o5mMaxStoredLen {
+		e.store(k + "\x00" + v)
+	}
+	return buf
+}
+
+func (e *O5MEncoder) appendString(buf []byte, s string) []byte {
+	if ref, ok := e.reference(s); ok {
+		return appendUnsigned(buf, ref)
+	}
+	buf = append(buf, 0)
+	buf = append(buf, s...)
+	buf = append(buf, 0)
+	if len(s) <= o5mMaxStoredLen {
+		e.store(s)
+	}
+	return buf
+}
+
+// reference returns the table reference of s, if s is still in the table.
+func (e *O5MEncoder) reference(s string) (uint64, bool) {
+	pos, ok := e.strings[s]
+	if !ok || e.tablePos-pos > o5mTableSize {
+		return 0, false
+	}
+	return uint64(e.tablePos - pos), true
+}
+
+func (e *O5MEncoder) store(s string) {
+	e.strings[s] = e.tablePos
+	e.tablePos++
+	// Drop strings that have fallen out of the table, so the map does not
+	// grow without bounds.
+	if len(e.strings) > 4*o5mTableSize {
+		for k, pos := range e.strings {
+			if e.tablePos-pos > o5mTableSize {
+				delete(e.strings, k)
+			}
+		}
+	}
+}
+
+func (e *O5MEncoder) writeDataset(typ byte) error {
+	var head [1 + binary.MaxVarintLen64]byte
+	head[0] = typ
+	n := binary.PutUvarint(head[1:], uint64(len(e.buf)))
+	e.write(head[:1+n])
+	e.write(e.buf)
+	return e.err
+}
+
+func (e *O5MEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func appendUnsigned(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendSigned(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
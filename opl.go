@@ -0,0 +1,238 @@
+package gosmparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// An OPLDecoder reads and decodes OPL ("Object Per Line") data from an input
+// stream, as written by osmium.
+type OPLDecoder struct {
+	r        io.Reader
+	withInfo bool
+}
+
+// NewOPLDecoder returns a new decoder that reads OPL from r.
+func NewOPLDecoder(r io.Reader) *OPLDecoder {
+	return &OPLDecoder{r: r}
+}
+
+// NewOPLDecoderWithInfo returns a new decoder similar to NewOPLDecoder, but will
+// populate the Info field in the elements. Use this if you need meta data.
+func NewOPLDecoderWithInfo(r io.Reader) *OPLDecoder {
+	return &OPLDecoder{r: r, withInfo: true}
+}
+
+// Parse starts the parsing process that will stream data into the given OSMReader.
+// Elements are delivered sequentially in file order. Blank lines and lines
+// starting with # are skipped.
+func (d *OPLDecoder) Parse(o OSMReader) error {
+	sc := bufio.NewScanner(d.r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var lineNo int
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if err := d.parseLine(o, line); err != nil {
+			return fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	return sc.Err()
+}
+
+func (d *OPLDecoder) parseLine(o OSMReader, line string) error {
+	fields := strings.Fields(line)
+	kind := fields[0][0]
+	id, err := strconv.ParseInt(fields[0][1:], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q", fields[0])
+	}
+
+	var (
+		info     = Info{Visible: true}
+		tags     = map[string]string{}
+		lat, lon float64
+		nodeIDs  = []int64{}
+		members  = []RelationMember{}
+	)
+	for _, f := range fields[1:] {
+		val := f[1:]
+		switch f[0] {
+		case 'v':
+			info.Version, err = strconv.Atoi(val)
+		case 'd':
+			info.Visible = val != "D"
+		case 'c':
+			info.Changeset, err = strconv.ParseInt(val, 10, 64)
+		case 't':
+			if val != "" {
+				info.Timestamp, err = time.Parse(time.RFC3339, val)
+			}
+		case 'i':
+			info.UID, err = strconv.Atoi(val)
+		case 'u':
+			info.User, err = unescapeOPL(val)
+		case 'T':
+			tags, err = parseOPLTags(val)
+		case 'x':
+			if val != "" {
+				lon, err = strconv.ParseFloat(val, 64)
+			}
+		case 'y':
+			if val != "" {
+				lat, err = strconv.ParseFloat(val, 64)
+			}
+		case 'N':
+			nodeIDs, err = parseOPLNodes(val)
+		case 'M':
+			members, err = parseOPLMembers(val)
+		default:
+			err = fmt.Errorf("unknown field %q", f)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	el := Element{ID: id, Tags: tags}
+	if d.withInfo {
+		el.Info = &info
+	}
+	switch kind {
+	case 'n':
+		o.ReadNode(Node{Element: el, Lat: lat, Lon: lon})
+	case 'w':
+		o.ReadWay(Way{Element: el, NodeIDs: nodeIDs})
+	case 'r':
+		o.ReadRelation(Relation{Element: el, Members: members})
+	default:
+		return fmt.Errorf("unknown element type %q", kind)
+	}
+	return nil
+}
+
+func parseOPLTags(s string) (map[string]string, error) {
+	tags := map[string]string{}
+	if s == "" {
+		return tags, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid tag %q", kv)
+		}
+		k, err := unescapeOPL(kv[:i])
+		if err != nil {
+			return nil, err
+		}
+		v, err := unescapeOPL(kv[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func parseOPLNodes(s string) ([]int64, error) {
+	ids := []int64{}
+	if s == "" {
+		return ids, nil
+	}
+	for _, ref := range strings.Split(s, ",") {
+		if len(ref) < 2 || ref[0] != 'n' {
+			return nil, fmt.Errorf("invalid node reference %q", ref)
+		}
+		id, err := strconv.ParseInt(ref[1:], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func parseOPLMembers(s string) ([]RelationMember, error) {
+	members := []RelationMember{}
+	if s == "" {
+		return members, nil
+	}
+	for _, ref := range strings.Split(s, ",") {
+		at := strings.IndexByte(ref, '@')
+		if len(ref) < 2 || at < 0 {
+			return nil, fmt.Errorf("invalid member %q", ref)
+		}
+		var m RelationMember
+		switch ref[0] {
+		case 'n':
+			m.Type = NodeType
+		case 'w':
+			m.Type = WayType
+		case 'r':
+			m.Type = RelationType
+		default:
+			return nil, fmt.Errorf("invalid member type in %q", ref)
+		}
+		var err error
+		if m.ID, err = strconv.ParseInt(ref[1:at], 10, 64); err != nil {
+			return nil, err
+		}
+		if m.Role, err = unescapeOPL(ref[at+1:]); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// unescapeOPL decodes %hex% sequences into the corresponding characters.
+func unescapeOPL(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '%')
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start+1:], '%')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated escape sequence in %q", s)
+		}
+		end += start + 1
+		c, err := strconv.ParseUint(s[start+1:end], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(c)) {
+			return "", fmt.Errorf("invalid escape sequence %q", s[start:end+1])
+		}
+		b.WriteString(s[:start])
+		b.WriteRune(rune(c))
+		s = s[end+1:]
+	}
+}
+
+// escapeOPL encodes all characters that are not allowed verbatim in OPL. The
+// set of allowed characters is the same osmium uses.
+func escapeOPL(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if (c >= 0x21 && c <= 0x24) || (c >= 0x26 && c <= 0x2b) ||
+			(c >= 0x2d && c <= 0x3c) || (c >= 0x3e && c <= 0x3f) ||
+			(c >= 0x41 && c <= 0x7e) || (c >= 0xa1 && c <= 0xac) ||
+			(c >= 0xae && c <= 0x05ff) {
+			b.WriteRune(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%x%%", c)
+	}
+	return b.String()
+}
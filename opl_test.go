@@ -0,0 +1,89 @@
+package gosmparse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseOPLWithInfo(f *os.File, o OSMReader) error {
+	return NewOPLDecoderWithInfo(f).Parse(o)
+}
+
+func TestOPLMatchesXML(t *testing.T) {
+	xml := readFile(t, "testdata/history.osh", parseXMLWithInfo)
+	opl := readFile(t, "testdata/history.opl", parseOPLWithInfo)
+	assert.Equal(t, xml, opl)
+}
+
+func TestOPLRoundTrip(t *testing.T) {
+	orig := readFile(t, "testdata/history.osh", parseXMLWithInfo)
+
+	var buf bytes.Buffer
+	enc := NewOPLEncoder(&buf)
+	for _, n := range orig.Nodes {
+		assert.Nil(t, enc.WriteNode(n))
+	}
+	for _, w := range orig.Ways {
+		assert.Nil(t, enc.WriteWay(w))
+	}
+	for _, r := range orig.Rels {
+		assert.Nil(t, enc.WriteRelation(r))
+	}
+	assert.Nil(t, enc.Close())
+
+	expected, err := ioutil.ReadFile("testdata/history.opl")
+	assert.Nil(t, err)
+	assert.Equal(t, strings.SplitN(string(expected), "\n", 2)[1], buf.String())
+}
+
+func TestOPLEscaping(t *testing.T) {
+	tags := map[string]string{"name": "a=b, c@d 100% ✓", "note": "line\nbreak"}
+	r := Relation{
+		Element: Element{ID: -5, Tags: tags},
+		Members: []RelationMember{{ID: 7, Type: RelationType, Role: "sub area"}},
+	}
+
+	var buf bytes.Buffer
+	enc := NewOPLEncoder(&buf)
+	assert.Nil(t, enc.WriteRelation(r))
+	assert.Nil(t, enc.Close())
+	assert.Equal(t, "r-5 Tname=a%3d%b%2c%%20%c%40%d%20%100%25%%20%%2713%,note=line%a%break Mr7@sub%20%area\n", buf.String())
+
+	decoded := &cachedReader{}
+	assert.Nil(t, NewOPLDecoder(&buf).Parse(decoded))
+	assert.Equal(t, []Relation{r}, decoded.Rels)
+}
+
+func TestOPLInvalid(t *testing.T) {
+	for _, line := range []string{
+		"x1 T",
+		"nfoo T",
+		"n1 Tkey",
+		"w1 N1,2",
+		"r1 Mq1@",
+		"n1 ubad%zz%",
+		"n1 unoend%20",
+		"n1 Q",
+	} {
+		err := NewOPLDecoder(strings.NewReader(line)).Parse(newMockOSMReader())
+		assert.NotNil(t, err, line)
+	}
+}
+
+func TestOPLBlankLines(t *testing.T) {
+	doc := "n1 T x1 y2\n \n\t\r\n  # comment\n\nw1 T Nn1\r\n"
+	cr := &cachedReader{}
+	assert.Nil(t, NewOPLDecoder(strings.NewReader(doc)).Parse(cr))
+	assert.Len(t, cr.Nodes, 1)
+	assert.Len(t, cr.Ways, 1)
+	assert.Equal(t, []int64{1}, cr.Ways[0].NodeIDs)
+
+	for _, doc := range []string{" ", "\t", " \t \n"} {
+		assert.Nil(t, NewOPLDecoder(strings.NewReader(doc)).Parse(newMockOSMReader()), doc)
+	}
+}
@@ -0,0 +1,96 @@
+package gosmparse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// An OPLEncoder writes elements in the OPL ("Object Per Line") format, one
+// element per line. Meta data is only written for elements that have an Info.
+type OPLEncoder struct {
+	w      *bufio.Writer
+	closed bool
+	err    error
+}
+
+// NewOPLEncoder returns a new encoder that writes to w.
+func NewOPLEncoder(w io.Writer) *OPLEncoder {
+	return &OPLEncoder{w: bufio.NewWriter(w)}
+}
+
+// WriteNode writes a node line.
+func (e *OPLEncoder) WriteNode(n Node) error {
+	e.writeHead('n', &n.Element)
+	if n.Info == nil || n.Info.Visible {
+		e.printf(" x%s y%s\n", formatCoord(n.Lon), formatCoord(n.Lat))
+	} else {
+		e.printf(" x y\n")
+	}
+	return e.err
+}
+
+// WriteWay writes a way line.
+func (e *OPLEncoder) WriteWay(w Way) error {
+	e.writeHead('w', &w.Element)
+	refs := make([]string, len(w.NodeIDs))
+	for i, id := range w.NodeIDs {
+		refs[i] = fmt.Sprintf("n%d", id)
+	}
+	e.printf(" N%s\n", strings.Join(refs, ","))
+	return e.err
+}
+
+// WriteRelation writes a relation line.
+func (e *OPLEncoder) WriteRelation(r Relation) error {
+	e.writeHead('r', &r.Element)
+	members := make([]string, len(r.Members))
+	for i, m := range r.Members {
+		members[i] = fmt.Sprintf("%c%d@%s", m.Type.String()[0], m.ID, escapeOPL(m.Role))
+	}
+	e.printf(" M%s\n", strings.Join(members, ","))
+	return e.err
+}
+
+// Close flushes all buffered data. It does not close the underlying writer.
+func (e *OPLEncoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *OPLEncoder) writeHead(kind byte, el *Element) {
+	if e.closed && e.err == nil {
+		e.err = fmt.Errorf("encoder has already been closed")
+	}
+	e.printf("%c%d", kind, el.ID)
+	if i := el.Info; i != nil {
+		visible := 'V'
+		if !i.Visible {
+			visible = 'D'
+		}
+		ts := ""
+		if !i.Timestamp.IsZero() {
+			ts = formatTimestamp(i.Timestamp)
+		}
+		e.printf(" v%d d%c c%d t%s i%d u%s", i.Version, visible, i.Changeset, ts, i.UID, escapeOPL(i.User))
+	}
+	tags := make([]string, 0, len(el.Tags))
+	for _, k := range sortedKeys(el.Tags) {
+		tags = append(tags, escapeOPL(k)+"="+escapeOPL(el.Tags[k]))
+	}
+	e.printf(" T%s", strings.Join(tags, ","))
+}
+
+func (e *OPLEncoder) printf(format string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
@@ -0,0 +1,10 @@
+/*
+Package replication follows the replication diffs of an OpenStreetMap server
+(minutely, hourly or daily), which allow to keep a data file up to date.
+
+A replication directory contains a state.txt with the latest sequence number
+and, for every sequence number, a state file and an OsmChange file, e.g.
+000/123/456.state.txt and 000/123/456.osc.gz. The change files can be read
+with gosmparse.NewChangeDecoder and applied with gosmparse.ApplyChanges.
+*/
+package replication
@@ -0,0 +1,86 @@
+package replication
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Base URLs of the replication diffs of openstreetmap.org.
+const (
+	MinutelyURL = "https://planet.openstreetmap.org/replication/minute"
+	HourlyURL   = "https://planet.openstreetmap.org/replication/hour"
+	DailyURL    = "https://planet.openstreetmap.org/replication/day"
+)
+
+// ErrNotFound is returned by a Fetcher if a file does not exist.
+var ErrNotFound = errors.New("replication file not found")
+
+// A Fetcher retrieves the files of a replication directory. Paths are
+// relative to the directory and use slashes, e.g. "000/123/456.osc.gz".
+type Fetcher interface {
+	Fetch(path string) (io.ReadCloser, error)
+}
+
+// HTTPFetcher fetches the files from a replication server.
+type HTTPFetcher struct {
+	// BaseURL is the URL of the replication directory, e.g. MinutelyURL.
+	BaseURL string
+	// Client is used for the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// NewHTTPFetcher returns a fetcher for the replication directory at baseURL.
+func NewHTTPFetcher(baseURL string) *HTTPFetcher {
+	return &HTTPFetcher{BaseURL: baseURL}
+}
+
+// Fetch implements Fetcher.
+func (f *HTTPFetcher) Fetch(path string) (io.ReadCloser, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := strings.TrimSuffix(f.BaseURL, "/") + "/" + path
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gosmparse")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", url, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// DirFetcher reads the files from a local replication directory.
+type DirFetcher struct {
+	Dir string
+}
+
+// NewDirFetcher returns a fetcher for the replication directory dir.
+func NewDirFetcher(dir string) *DirFetcher {
+	return &DirFetcher{Dir: dir}
+}
+
+// Fetch implements Fetcher.
+func (f *DirFetcher) Fetch(path string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(f.Dir, filepath.FromSlash(path)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	return file, err
+}
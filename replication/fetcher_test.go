@@ -0,0 +1,33 @@
+package replication
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchers(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	for name, f := range map[string]Fetcher{
+		"dir":  NewDirFetcher("testdata"),
+		"http": NewHTTPFetcher(srv.URL + "/"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := f.Fetch("000/000/003.state.txt")
+			assert.Nil(t, err)
+			buf, err := ioutil.ReadAll(r)
+			assert.Nil(t, err)
+			assert.Nil(t, r.Close())
+			assert.Contains(t, string(buf), "sequenceNumber=3\n")
+
+			_, err = f.Fetch("000/000/011.state.txt")
+			assert.True(t, errors.Is(err, ErrNotFound), err)
+		})
+	}
+}
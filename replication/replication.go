@@ -0,0 +1,88 @@
+package replication
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// CurrentState fetches the latest state of the replication directory.
+func CurrentState(f Fetcher) (State, error) {
+	return fetchState(f, "state.txt")
+}
+
+// FetchState fetches the state of a sequence number.
+func FetchState(f Fetcher, seq int64) (State, error) {
+	return fetchState(f, StatePath(seq))
+}
+
+func fetchState(f Fetcher, path string) (State, error) {
+	r, err := f.Fetch(path)
+	if err != nil {
+		return State{}, err
+	}
+	defer r.Close()
+	s, err := ParseState(r)
+	if err != nil {
+		return s, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// FetchDiff fetches the OsmChange file of a sequence number. The content is
+// gzip compressed; gosmparse.NewChangeDecoder decompresses it automatically.
+// The caller needs to close the returned reader.
+func FetchDiff(f Fetcher, seq int64) (io.ReadCloser, error) {
+	return f.Fetch(DiffPath(seq))
+}
+
+// FindSequence returns the sequence number of the latest state with a
+// timestamp at or before t. A file that is up to date as of t (e.g. with the
+// replication timestamp of its header) is updated by applying the diffs
+// starting with the following sequence number.
+//
+// The states are searched backwards from the current state with increasing
+// steps and then with a binary search, so only a few states are fetched.
+func FindSequence(f Fetcher, t time.Time) (int64, error) {
+	current, err := CurrentState(f)
+	if err != nil {
+		return 0, err
+	}
+	if !current.Timestamp.After(t) {
+		return current.SequenceNumber, nil
+	}
+
+	// Find a state at or before t. hi is always after t.
+	hi, lo := current.SequenceNumber, int64(0)
+	for step := int64(1); ; step *= 2 {
+		lo = hi - step
+		if lo < 0 {
+			lo = 0
+		}
+		s, err := FetchState(f, lo)
+		if err != nil {
+			return 0, err
+		}
+		if !s.Timestamp.After(t) {
+			break
+		}
+		if lo == 0 {
+			return 0, fmt.Errorf("%s is before the first state", t.Format(time.RFC3339))
+		}
+		hi = lo
+	}
+
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		s, err := FetchState(f, mid)
+		if err != nil {
+			return 0, err
+		}
+		if s.Timestamp.After(t) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return lo, nil
+}
@@ -0,0 +1,78 @@
+package replication
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomersch/gosmparse"
+)
+
+// countingFetcher counts the fetched files.
+type countingFetcher struct {
+	Fetcher
+	count int
+}
+
+func (f *countingFetcher) Fetch(path string) (io.ReadCloser, error) {
+	f.count++
+	return f.Fetcher.Fetch(path)
+}
+
+func TestCurrentState(t *testing.T) {
+	s, err := CurrentState(NewDirFetcher("testdata"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), s.SequenceNumber)
+
+	s, err = FetchState(NewDirFetcher("testdata"), 4)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), s.SequenceNumber)
+	assert.True(t, time.Date(2020, 1, 1, 4, 0, 0, 0, time.UTC).Equal(s.Timestamp))
+}
+
+func TestFindSequence(t *testing.T) {
+	hour := func(h, m int) time.Time { return time.Date(2020, 1, 1, h, m, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		time     time.Time
+		expected int64
+	}{
+		{hour(0, 0), 0},
+		{hour(0, 30), 0},
+		{hour(3, 0), 3},
+		{hour(6, 59), 6},
+		{hour(9, 30), 9},
+		{hour(10, 0), 10},
+		{hour(12, 0), 10},
+	} {
+		f := &countingFetcher{Fetcher: NewDirFetcher("testdata")}
+		seq, err := FindSequence(f, tc.time)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, seq, tc.time)
+		assert.True(t, f.count <= 8, "%d states fetched", f.count)
+	}
+
+	_, err := FindSequence(NewDirFetcher("testdata"), time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NotNil(t, err)
+}
+
+type changeCounter struct {
+	elements int
+}
+
+func (c *changeCounter) ReadNode(gosmparse.Node)         { c.elements++ }
+func (c *changeCounter) ReadWay(gosmparse.Way)           { c.elements++ }
+func (c *changeCounter) ReadRelation(gosmparse.Relation) { c.elements++ }
+func (c *changeCounter) ReadAction(gosmparse.Action)     {}
+
+func TestFetchDiff(t *testing.T) {
+	r, err := FetchDiff(NewDirFetcher("testdata"), 10)
+	assert.Nil(t, err)
+	defer r.Close()
+	c := &changeCounter{}
+	assert.Nil(t, gosmparse.NewChangeDecoder(r).Parse(c))
+	assert.NotZero(t, c.elements)
+
+	_, err = FetchDiff(NewDirFetcher("testdata"), 9)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,95 @@
+package replication
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// State describes a replication state: all changes up to Timestamp are
+// contained in the diffs up to SequenceNumber.
+type State struct {
+	SequenceNumber int64
+	Timestamp      time.Time
+}
+
+// ParseState reads a state file (state.txt). The file uses the format of Java
+// properties, so colons in the timestamp are escaped with backslashes.
+func ParseState(r io.Reader) (State, error) {
+	var (
+		s                    State
+		hasSeq, hasTimestamp bool
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		pos := strings.IndexAny(line, "=:")
+		if pos < 0 {
+			return s, fmt.Errorf("invalid line in state file: %q", line)
+		}
+		key, value := strings.TrimSpace(line[:pos]), unescape(strings.TrimSpace(line[pos+1:]))
+		switch key {
+		case "sequenceNumber":
+			seq, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return s, fmt.Errorf("invalid sequence number %q", value)
+			}
+			s.SequenceNumber, hasSeq = seq, true
+		case "timestamp":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return s, fmt.Errorf("invalid timestamp %q", value)
+			}
+			s.Timestamp, hasTimestamp = t, true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return s, err
+	}
+	if !hasSeq {
+		return s, fmt.Errorf("state file has no sequence number")
+	}
+	if !hasTimestamp {
+		return s, fmt.Errorf("state file has no timestamp")
+	}
+	return s, nil
+}
+
+// unescape removes the backslashes of escaped characters.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// SequencePath returns the path of the files of a sequence number without
+// extension, e.g. "000/123/456" for 123456.
+func SequencePath(seq int64) string {
+	return fmt.Sprintf("%03d/%03d/%03d", seq/1000000, seq/1000%1000, seq%1000)
+}
+
+// StatePath returns the path of the state file of a sequence number, e.g.
+// "000/123/456.state.txt".
+func StatePath(seq int64) string {
+	return SequencePath(seq) + ".state.txt"
+}
+
+// DiffPath returns the path of the OsmChange file of a sequence number, e.g.
+// "000/123/456.osc.gz".
+func DiffPath(seq int64) string {
+	return SequencePath(seq) + ".osc.gz"
+}
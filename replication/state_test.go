@@ -0,0 +1,39 @@
+package replication
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseState(t *testing.T) {
+	s, err := ParseState(strings.NewReader(`#Sat Oct 19 07:21:02 UTC 2024
+txnMaxQueried=123
+sequenceNumber=6303522
+timestamp=2024-10-19T07\:20\:53Z
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6303522), s.SequenceNumber)
+	assert.True(t, time.Date(2024, 10, 19, 7, 20, 53, 0, time.UTC).Equal(s.Timestamp))
+
+	for _, invalid := range []string{
+		"timestamp=2024-10-19T07\\:20\\:53Z\n",
+		"sequenceNumber=1\n",
+		"sequenceNumber=x\ntimestamp=2024-10-19T07\\:20\\:53Z\n",
+		"sequenceNumber=1\ntimestamp=yesterday\n",
+		"garbage\n",
+	} {
+		_, err := ParseState(strings.NewReader(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestSequencePath(t *testing.T) {
+	assert.Equal(t, "000/000/000", SequencePath(0))
+	assert.Equal(t, "000/123/456", SequencePath(123456))
+	assert.Equal(t, "006/303/522", SequencePath(6303522))
+	assert.Equal(t, "000/000/042.state.txt", StatePath(42))
+	assert.Equal(t, "001/000/000.osc.gz", DiffPath(1000000))
+}
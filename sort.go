@@ -0,0 +1,305 @@
+package gosmparse
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+const defaultSortMaxElements = 4000000
+
+// sortMaxRuns is the number of runs that are merged at once, which limits the
+// number of open files. More runs are merged in several passes.
+var sortMaxRuns = 64
+
+// SortOptions configures Sort.
+type SortOptions struct {
+	// MaxElements is the number of elements that are held in memory. If the
+	// input contains more elements, sorted runs are written to temporary files
+	// and merged afterwards. The default is 4 million elements.
+	MaxElements int
+	// TempDir is the directory for the temporary files. If empty, the default
+	// directory for temporary files is used.
+	TempDir string
+}
+
+// Sort reads the PBF file r and writes its elements as PBF to w, sorted by
+// type, ID and version (for history files). The header of the input is kept
+// and the optional feature Sort.Type_then_ID is added.
+//
+// Inputs that do not fit into memory (see SortOptions.MaxElements) are sorted
+// externally: sorted runs are written to temporary files, which are merged
+// into the output, in several passes if there are many of them.
+func Sort(w io.Writer, r io.Reader, opts SortOptions) error {
+	if opts.MaxElements <= 0 {
+		opts.MaxElements = defaultSortMaxElements
+	}
+	s := &sorter{opts: opts}
+	defer s.cleanup()
+	if err := NewDecoderWithInfo(r).Parse(s); err != nil {
+		return err
+	}
+	if s.err != nil {
+		return s.err
+	}
+
+	enc := NewEncoder(w)
+	enc.Header = s.header
+	enc.Header.OptionalFeatures = appendFeatures(enc.Header.OptionalFeatures, FeatureSortTypeThenID)
+	s.sortBuffer()
+	if len(s.runs) == 0 {
+		for i := range s.buf {
+			if err := s.buf[i].write(enc); err != nil {
+				return err
+			}
+		}
+		return enc.Close()
+	}
+
+	if len(s.buf) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	if err := s.reduceRuns(); err != nil {
+		return err
+	}
+	err := s.mergeFiles(s.runs, func(e *entity) error {
+		return e.write(enc)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// sorter collects the elements and spills them into sorted runs whenever
+// the buffer is full.
+type sorter struct {
+	opts   SortOptions
+	header Header
+
+	mtx  sync.Mutex
+	buf  []entity
+	runs []string
+	// temp contains all temporary files, including the ones that have been
+	// merged already.
+	temp []string
+	err  error
+}
+
+func (s *sorter) ReadHeader(h Header) {
+	s.header = h
+}
+
+func (s *sorter) ReadNode(n Node) {
+	s.add(entity{Type: NodeType, Node: n})
+}
+
+func (s *sorter) ReadWay(w Way) {
+	s.add(entity{Type: WayType, Way: w})
+}
+
+func (s *sorter) ReadRelation(r Relation) {
+	s.add(entity{Type: RelationType, Relation: r})
+}
+
+func (s *sorter) add(e entity) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.buf = append(s.buf, e)
+	if len(s.buf) >= s.opts.MaxElements {
+		s.sortBuffer()
+		s.err = s.spill()
+	}
+}
+
+func (s *sorter) sortBuffer() {
+	sort.Slice(s.buf, func(i, j int) bool {
+		return s.buf[i].less(&s.buf[j])
+	})
+}
+
+// spill writes the sorted buffer to a temporary file and empties it.
+func (s *sorter) spill() error {
+	name, err := s.writeRun(func(enc *gob.Encoder) error {
+		for i := range s.buf {
+			if err := enc.Encode(&s.buf[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, name)
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// writeRun creates a temporary file and calls fn to encode a sorted run into
+// it. It returns the name of the file.
+func (s *sorter) writeRun(fn func(enc *gob.Encoder) error) (string, error) {
+	f, err := ioutil.TempFile(s.opts.TempDir, "gosmparse-sort-")
+	if err != nil {
+		return "", err
+	}
+	s.temp = append(s.temp, f.Name())
+	bw := bufio.NewWriter(f)
+	if err := fn(gob.NewEncoder(bw)); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// reduceRuns merges groups of consecutive runs into new runs until there are
+// at most sortMaxRuns of them.
+func (s *sorter) reduceRuns() error {
+	for len(s.runs) > sortMaxRuns {
+		var merged []string
+		for i := 0; i < len(s.runs); i += sortMaxRuns {
+			group := s.runs[i:]
+			if len(group) > sortMaxRuns {
+				group = group[:sortMaxRuns]
+			}
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+			name, err := s.writeRun(func(enc *gob.Encoder) error {
+				return s.mergeFiles(group, func(e *entity) error {
+					return enc.Encode(e)
+				})
+			})
+			if err != nil {
+				return err
+			}
+			for _, n := range group {
+				os.Remove(n)
+			}
+			merged = append(merged, name)
+		}
+		s.runs = merged
+	}
+	return nil
+}
+
+// mergeFiles calls fn for the elements of the runs in the given files in
+// sorted order.
+func (s *sorter) mergeFiles(names []string, fn func(e *entity) error) error {
+	runs := make([]*run, len(names))
+	for i, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		runs[i] = gobRun(i, f)
+	}
+	return mergeRuns(runs, func(e *entity, _ *run) error {
+		return fn(e)
+	})
+}
+
+func (s *sorter) cleanup() {
+	for _, name := range s.temp {
+		os.Remove(name)
+	}
+}
+
+// run is a sorted stream of elements that is read during a merge.
+type run struct {
+	// index is the position of the run; elements that are equal are
+	// delivered in the order of their runs.
+	index int
+	read  func() (entity, bool, error)
+	head  entity
+}
+
+// next reads the next element into head. It returns false at the end of the
+// run.
+func (r *run) next() (bool, error) {
+	e, ok, err := r.read()
+	r.head = e
+	return ok, err
+}
+
+// gobRun returns a run that reads the elements of a temporary file.
+func gobRun(index int, r io.Reader) *run {
+	dec := gob.NewDecoder(bufio.NewReader(r))
+	return &run{index: index, read: func() (entity, bool, error) {
+		var e entity
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return e, false, nil
+		}
+		return e, err == nil, err
+	}}
+}
+
+// runHeap orders the runs by their next element.
+type runHeap []*run
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if h[i].head.less(&h[j].head) {
+		return true
+	}
+	if h[j].head.less(&h[i].head) {
+		return false
+	}
+	return h[i].index < h[j].index
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// mergeRuns calls fn for the elements of all runs in sorted order.
+func mergeRuns(runs []*run, fn func(e *entity, r *run) error) error {
+	h := make(runHeap, 0, len(runs))
+	for _, r := range runs {
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+	for len(h) > 0 {
+		r := h[0]
+		if err := fn(&r.head, r); err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
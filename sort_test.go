@@ -0,0 +1,95 @@
+package gosmparse
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unsortedTestFile(t *testing.T) *bytes.Buffer {
+	info := func(v int) *Info {
+		return &Info{Version: v, Timestamp: time.Unix(int64(v)*1000, 0), Visible: true}
+	}
+	cr := &cachedReader{
+		Rels: []Relation{
+			{Element: Element{ID: 3, Info: info(1)}, Members: []RelationMember{{ID: 1, Type: NodeType}}},
+			{Element: Element{ID: 1, Info: info(1)}, Members: []RelationMember{{ID: 2, Type: WayType}}},
+		},
+		Ways: []Way{
+			{Element: Element{ID: 2, Info: info(1)}, NodeIDs: []int64{1, 2}},
+			{Element: Element{ID: 1, Info: info(1)}, NodeIDs: []int64{2, 3}},
+		},
+		Nodes: []Node{
+			{Element: Element{ID: 3, Info: info(2)}, Lat: 1},
+			{Element: Element{ID: -1, Info: info(1)}, Lat: 2},
+			{Element: Element{ID: 3, Info: info(1)}, Lat: 3},
+			{Element: Element{ID: 2, Info: info(1)}, Lat: 4},
+			{Element: Element{ID: 1, Info: info(1)}, Lat: 5},
+		},
+	}
+	// write relations and ways first, so the types are unsorted as well
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Header = Header{WritingProgram: "test", OptionalFeatures: []string{"Has_Metadata"}}
+	enc.BlockSize = 2
+	for _, r := range cr.Rels {
+		assert.Nil(t, enc.WriteRelation(r))
+	}
+	for _, n := range cr.Nodes {
+		assert.Nil(t, enc.WriteNode(n))
+	}
+	for _, w := range cr.Ways {
+		assert.Nil(t, enc.WriteWay(w))
+	}
+	assert.Nil(t, enc.Close())
+	return &buf
+}
+
+func TestSort(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "gosmparse")
+	assert.Nil(t, err)
+
+	defer func(n int) { sortMaxRuns = n }(sortMaxRuns)
+	for _, tc := range []struct{ maxElements, maxRuns int }{
+		{0, 64}, {1, 64}, {2, 64}, {4, 64},
+		// more runs than can be merged at once
+		{1, 2}, {1, 3}, {2, 2},
+	} {
+		sortMaxRuns = tc.maxRuns
+		var out bytes.Buffer
+		err := Sort(&out, unsortedTestFile(t), SortOptions{MaxElements: tc.maxElements, TempDir: tmp})
+		assert.Nil(t, err)
+
+		cr := &headerCachedReader{}
+		dec := NewDecoderWithInfo(&out)
+		dec.Workers = 1
+		assert.Nil(t, dec.Parse(cr))
+
+		var order []string
+		for _, n := range cr.Nodes {
+			order = append(order, fmt.Sprintf("n%dv%d", n.ID, n.Info.Version))
+		}
+		for _, w := range cr.Ways {
+			order = append(order, fmt.Sprintf("w%d", w.ID))
+		}
+		for _, r := range cr.Rels {
+			order = append(order, fmt.Sprintf("r%d", r.ID))
+		}
+		assert.Equal(t, []string{"n-1v1", "n1v1", "n2v1", "n3v1", "n3v2", "w1", "w2", "r1", "r3"}, order)
+		assert.Equal(t, 5.0, cr.Nodes[1].Lat)
+		assert.Equal(t, []int64{2, 3}, cr.Ways[0].NodeIDs)
+		assert.Equal(t, "test", cr.Header.WritingProgram)
+		assert.Equal(t, []string{"Has_Metadata", FeatureSortTypeThenID}, cr.Header.OptionalFeatures)
+	}
+
+	// temporary files are removed
+	files, err := ioutil.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+	assert.Nil(t, os.Remove(tmp))
+}
@@ -0,0 +1,67 @@
+package gosmparse
+
+import (
+	"io"
+	"time"
+)
+
+// TimeFilter reads the history PBF file r and writes the state of the data at
+// time t as regular PBF file to w: of every element, the latest version with a
+// timestamp at or before t is written, unless that version is deleted
+// (Info.Visible is false). Elements that have been created after t are
+// omitted.
+//
+// The input needs to be sorted by type, ID and version (see Sort) and needs to
+// contain meta data. It is streamed, so the memory usage does not depend on
+// its size. The header of the input is kept, except for the history feature;
+// the replication timestamp is set to t.
+func TimeFilter(w io.Writer, r io.Reader, t time.Time) error {
+	done := make(chan struct{})
+	defer close(done)
+	s := newPBFStream(r, done)
+
+	enc := NewEncoder(w)
+	var started bool
+	err := groupVersions(s.run(0), func(versions []entity) error {
+		if !started {
+			started = true
+			enc.Header = timeFilterHeader(s.header, t)
+		}
+		// Use the latest version up to t.
+		latest := -1
+		for i := range versions {
+			if !versions[i].element().Info.Timestamp.After(t) {
+				latest = i
+			}
+		}
+		if latest < 0 || !versions[latest].element().Info.Visible {
+			return nil
+		}
+		return versions[latest].write(enc)
+	})
+	if err != nil {
+		return err
+	}
+	if !started {
+		enc.Header = timeFilterHeader(s.header, t)
+	}
+	return enc.Close()
+}
+
+func timeFilterHeader(h Header, t time.Time) Header {
+	h.RequiredFeatures = withoutFeature(h.RequiredFeatures, FeatureHistorical)
+	h.OptionalFeatures = withoutFeature(h.OptionalFeatures, FeatureHistorical)
+	h.ReplicationTimestamp = t
+	h.ReplicationSequenceNumber = 0
+	return h
+}
+
+func withoutFeature(list []string, feature string) []string {
+	var res []string
+	for _, f := range list {
+		if f != feature {
+			res = append(res, f)
+		}
+	}
+	return res
+}
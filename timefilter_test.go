@@ -0,0 +1,73 @@
+package gosmparse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sortedHistoryFile(t *testing.T) *bytes.Buffer {
+	f, err := os.Open("testdata/history.osh.pbf")
+	assert.Nil(t, err)
+	defer f.Close()
+	var buf bytes.Buffer
+	assert.Nil(t, Sort(&buf, f, SortOptions{}))
+	return &buf
+}
+
+func TestTimeFilter(t *testing.T) {
+	for _, tc := range []struct {
+		time     time.Time
+		expected []string
+	}{
+		{time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), []string{"n1v1", "n2v1", "w1v1", "r1v1"}},
+		{time.Date(2019, 4, 1, 19, 0, 0, 0, time.UTC), []string{"n1v2", "w1v1", "w2v2", "r1v1"}},
+		{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), []string{"n1v2", "w1v1", "w2v2", "r1v1"}},
+	} {
+		var out bytes.Buffer
+		assert.Nil(t, TimeFilter(&out, sortedHistoryFile(t), tc.time))
+
+		cr := &headerCachedReader{}
+		dec := NewDecoderWithInfo(&out)
+		dec.Workers = 1
+		assert.Nil(t, dec.Parse(cr))
+
+		var elems []string
+		for _, n := range cr.Nodes {
+			elems = append(elems, fmt.Sprintf("n%dv%d", n.ID, n.Info.Version))
+		}
+		for _, w := range cr.Ways {
+			elems = append(elems, fmt.Sprintf("w%dv%d", w.ID, w.Info.Version))
+		}
+		for _, r := range cr.Rels {
+			elems = append(elems, fmt.Sprintf("r%dv%d", r.ID, r.Info.Version))
+		}
+		assert.Equal(t, tc.expected, elems, tc.time)
+		assert.False(t, cr.Header.HasFeature(FeatureHistorical))
+		assert.True(t, cr.Header.HasFeature(FeatureSortTypeThenID))
+		assert.True(t, tc.time.Equal(cr.Header.ReplicationTimestamp))
+	}
+}
+
+func TestTimeFilterUnsorted(t *testing.T) {
+	f, err := os.Open("testdata/history.osh.pbf")
+	assert.Nil(t, err)
+	defer f.Close()
+	var out bytes.Buffer
+	err = TimeFilter(&out, f, time.Now())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not sorted")
+}
+
+func TestTimeFilterWithoutInfo(t *testing.T) {
+	buf := encodePBF(t, Header{WritingProgram: "test"}, &cachedReader{Nodes: []Node{{Element: Element{ID: 1}}}})
+	var out bytes.Buffer
+	err := TimeFilter(&out, buf, time.Now())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "node 1 has no meta data")
+}
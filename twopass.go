@@ -0,0 +1,122 @@
+package gosmparse
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A LocationDecoder parses a PBF file in two passes, in order to deliver ways
+// together with the locations of their nodes. The first pass stores the node
+// locations in Store and delivers the nodes, the second pass delivers the ways
+// with populated Coords and the relations.
+//
+// Coords always has the same length as NodeIDs. Nodes whose location is not in
+// Store get MissingLatLon, so ways with missing nodes can be detected with
+// Way.CoordsComplete.
+type LocationDecoder struct {
+	// Store holds the node locations between both passes.
+	Store LocationStore
+	// MissingNode is called for every node reference of a way whose location
+	// is unknown; the corresponding entry in Coords is MissingLatLon. It may
+	// be called concurrently.
+	MissingNode func(w Way, nodeID int64)
+	// WithInfo populates the Info field of the elements, like
+	// NewDecoderWithInfo does.
+	WithInfo bool
+
+	r io.ReadSeeker
+}
+
+// NewLocationDecoder returns a new decoder that reads from r, which needs to be
+// seekable for the second pass.
+func NewLocationDecoder(r io.ReadSeeker, store LocationStore) *LocationDecoder {
+	return &LocationDecoder{r: r, Store: store}
+}
+
+// Parse runs both passes and streams the data into the given OSMReader. If o
+// implements HeaderReader, the header is delivered in the first pass.
+func (d *LocationDecoder) Parse(o OSMReader) error {
+	np := &nodePass{o: o, collector: LocationCollector{Store: d.Store}}
+	if err := d.decoder().Parse(np); err != nil {
+		return err
+	}
+	if err := np.collector.Err(); err != nil {
+		return err
+	}
+
+	if _, err := d.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	wp := &wayPass{o: o, d: d}
+	if err := d.decoder().Parse(wp); err != nil {
+		return err
+	}
+	return wp.err
+}
+
+func (d *LocationDecoder) decoder() *Decoder {
+	if d.WithInfo {
+		return NewDecoderWithInfo(d.r)
+	}
+	return NewDecoder(d.r)
+}
+
+// nodePass stores the node locations and forwards the nodes.
+type nodePass struct {
+	o         OSMReader
+	collector LocationCollector
+}
+
+func (p *nodePass) ReadHeader(h Header) {
+	if hr, ok := p.o.(HeaderReader); ok {
+		hr.ReadHeader(h)
+	}
+}
+
+func (p *nodePass) ReadNode(n Node) {
+	p.collector.ReadNode(n)
+	p.o.ReadNode(n)
+}
+
+func (p *nodePass) ReadWay(Way) {}
+
+func (p *nodePass) ReadRelation(Relation) {}
+
+// wayPass resolves the way geometries and forwards ways and relations.
+type wayPass struct {
+	o OSMReader
+	d *LocationDecoder
+
+	mtx sync.Mutex
+	err error
+}
+
+func (p *wayPass) ReadNode(Node) {}
+
+func (p *wayPass) ReadWay(w Way) {
+	w.Coords = make([]LatLon, len(w.NodeIDs))
+	for i, id := range w.NodeIDs {
+		ll, ok, err := p.d.Store.Get(id)
+		if err != nil {
+			p.mtx.Lock()
+			if p.err == nil {
+				p.err = fmt.Errorf("way %d: %v", w.ID, err)
+			}
+			p.mtx.Unlock()
+			return
+		}
+		if !ok {
+			if p.d.MissingNode != nil {
+				p.d.MissingNode(w, id)
+			}
+			ll = MissingLatLon
+		}
+		w.Coords[i] = ll
+	}
+	p.o.ReadWay(w)
+}
+
+func (p *wayPass) ReadRelation(r Relation) {
+	p.o.ReadRelation(r)
+}
@@ -0,0 +1,54 @@
+package gosmparse
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationDecoder(t *testing.T) {
+	cr := &cachedReader{
+		Nodes: []Node{
+			{Element: Element{ID: 1}, Lat: 1, Lon: 2},
+			{Element: Element{ID: 2}, Lat: 3, Lon: 4},
+		},
+		Ways: []Way{
+			{Element: Element{ID: 10}, NodeIDs: []int64{1, 2}},
+			{Element: Element{ID: 11}, NodeIDs: []int64{2, 3, 1}},
+		},
+		Rels: []Relation{
+			{Element: Element{ID: 20}, Members: []RelationMember{{ID: 10, Type: WayType}}},
+		},
+	}
+	buf := encodePBF(t, Header{WritingProgram: "test"}, cr)
+
+	var (
+		mtx     sync.Mutex
+		missing []int64
+	)
+	d := NewLocationDecoder(bytes.NewReader(buf.Bytes()), NewMapLocationStore())
+	d.MissingNode = func(w Way, id int64) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		missing = append(missing, w.ID, id)
+	}
+	out := &headerCachedReader{}
+	assert.Nil(t, d.Parse(out))
+	sortCachedReader(&out.cachedReader)
+
+	assert.Equal(t, "test", out.Header.WritingProgram)
+	assert.Len(t, out.Nodes, 2)
+	assert.Len(t, out.Ways, 2)
+	assert.Equal(t, []LatLon{{1, 2}, {3, 4}}, out.Ways[0].Coords)
+	assert.True(t, out.Ways[0].CoordsComplete())
+	coords := out.Ways[1].Coords
+	assert.Len(t, coords, 3)
+	assert.Equal(t, LatLon{3, 4}, coords[0])
+	assert.True(t, coords[1].Missing())
+	assert.Equal(t, LatLon{1, 2}, coords[2])
+	assert.False(t, out.Ways[1].CoordsComplete())
+	assert.Equal(t, []int64{11, 3}, missing)
+	assert.Len(t, out.Rels, 1)
+}
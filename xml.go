@@ -0,0 +1,187 @@
+package gosmparse
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// An XMLDecoder reads and decodes OSM XML data (.osm and .osh files) from an
+// input stream.
+type XMLDecoder struct {
+	r        io.Reader
+	withInfo bool
+}
+
+// NewXMLDecoder returns a new decoder that reads OSM XML from r.
+func NewXMLDecoder(r io.Reader) *XMLDecoder {
+	return &XMLDecoder{r: r}
+}
+
+// NewXMLDecoderWithInfo returns a new decoder similar to NewXMLDecoder, but will
+// populate the Info field in the elements. Use this if you need meta data.
+func NewXMLDecoderWithInfo(r io.Reader) *XMLDecoder {
+	return &XMLDecoder{r: r, withInfo: true}
+}
+
+// Parse starts the parsing process that will stream data into the given OSMReader.
+// In contrast to Decoder, elements are delivered sequentially in file order.
+func (d *XMLDecoder) Parse(o OSMReader) error {
+	return parseXML(xml.NewDecoder(d.r), o, d.withInfo, nil, nil)
+}
+
+// parseXML walks through all tokens of dec and hands every element to o. If
+// startFn is set, it is called for every start element before it is handled,
+// except for the children of nodes, ways and relations. Likewise, endFn is
+// called for every end element except for those of nodes, ways, relations and
+// their children.
+func parseXML(dec *xml.Decoder, o OSMReader, withInfo bool, startFn func(xml.StartElement) error, endFn func(xml.EndElement)) error {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if ee, ok := tok.(xml.EndElement); ok && endFn != nil {
+			endFn(ee)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if startFn != nil {
+			if err := startFn(se); err != nil {
+				return err
+			}
+		}
+		switch se.Name.Local {
+		case "node", "way", "relation":
+			var e xmlElement
+			if err := dec.DecodeElement(&e, &se); err != nil {
+				return err
+			}
+			if err := e.deliver(o, se.Name.Local, withInfo); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// xmlElement is the XML representation of a node, way or relation.
+type xmlElement struct {
+	ID        int64     `xml:"id,attr"`
+	Lat       float64   `xml:"lat,attr"`
+	Lon       float64   `xml:"lon,attr"`
+	Version   int       `xml:"version,attr"`
+	Timestamp time.Time `xml:"timestamp,attr"`
+	Changeset int64     `xml:"changeset,attr"`
+	UID       int       `xml:"uid,attr"`
+	User      string    `xml:"user,attr"`
+	Visible   *bool     `xml:"visible,attr"`
+
+	Tags    []xmlTag    `xml:"tag"`
+	Nds     []xmlNd     `xml:"nd"`
+	Members []xmlMember `xml:"member"`
+}
+
+type xmlTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type xmlNd struct {
+	Ref int64 `xml:"ref,attr"`
+}
+
+type xmlMember struct {
+	Type string `xml:"type,attr"`
+	Ref  int64  `xml:"ref,attr"`
+	Role string `xml:"role,attr"`
+}
+
+func (e *xmlElement) deliver(o OSMReader, kind string, withInfo bool) error {
+	switch kind {
+	case "node":
+		o.ReadNode(e.node(withInfo))
+	case "way":
+		o.ReadWay(e.way(withInfo))
+	case "relation":
+		r, err := e.relation(withInfo)
+		if err != nil {
+			return err
+		}
+		o.ReadRelation(r)
+	}
+	return nil
+}
+
+func (e *xmlElement) element(withInfo bool) Element {
+	el := Element{
+		ID:   e.ID,
+		Tags: make(map[string]string, len(e.Tags)),
+	}
+	for _, t := range e.Tags {
+		el.Tags[t.Key] = t.Value
+	}
+	if withInfo {
+		el.Info = &Info{
+			Version:   e.Version,
+			Timestamp: e.Timestamp,
+			Changeset: e.Changeset,
+			UID:       e.UID,
+			User:      e.User,
+			Visible:   e.Visible == nil || *e.Visible,
+		}
+	}
+	return el
+}
+
+func (e *xmlElement) node(withInfo bool) Node {
+	return Node{
+		Element: e.element(withInfo),
+		Lat:     e.Lat,
+		Lon:     e.Lon,
+	}
+}
+
+func (e *xmlElement) way(withInfo bool) Way {
+	w := Way{
+		Element: e.element(withInfo),
+		NodeIDs: make([]int64, len(e.Nds)),
+	}
+	for i, nd := range e.Nds {
+		w.NodeIDs[i] = nd.Ref
+	}
+	return w
+}
+
+func (e *xmlElement) relation(withInfo bool) (Relation, error) {
+	r := Relation{
+		Element: e.element(withInfo),
+		Members: make([]RelationMember, len(e.Members)),
+	}
+	for i, m := range e.Members {
+		mt, err := parseMemberType(m.Type)
+		if err != nil {
+			return r, fmt.Errorf("relation %d: %v", e.ID, err)
+		}
+		r.Members[i] = RelationMember{ID: m.Ref, Type: mt, Role: m.Role}
+	}
+	return r, nil
+}
+
+func parseMemberType(s string) (MemberType, error) {
+	switch s {
+	case "node":
+		return NodeType, nil
+	case "way":
+		return WayType, nil
+	case "relation":
+		return RelationType, nil
+	}
+	return 0, fmt.Errorf("unknown member type %q", s)
+}
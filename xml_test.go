@@ -0,0 +1,120 @@
+package gosmparse
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sortCachedReader(cr *cachedReader) {
+	sort.SliceStable(cr.Nodes, func(i, j int) bool { return cr.Nodes[i].ID < cr.Nodes[j].ID })
+	sort.SliceStable(cr.Ways, func(i, j int) bool { return cr.Ways[i].ID < cr.Ways[j].ID })
+	sort.SliceStable(cr.Rels, func(i, j int) bool { return cr.Rels[i].ID < cr.Rels[j].ID })
+}
+
+func TestXMLMatchesPBF(t *testing.T) {
+	twins := []struct {
+		XML, PBF string
+	}{
+		{"testdata/base.osm", "testdata/base.pbf"},
+		{"testdata/node_kv.osm", "testdata/node_kv.osm.pbf"},
+		{"testdata/way_kv.osm", "testdata/way_kv.osm.pbf"},
+		{"testdata/relation.osm", "testdata/relation.pbf"},
+		{"testdata/relation_kv.osm", "testdata/relation_kv.osm.pbf"},
+		{"testdata/stringtable.osm", "testdata/stringtable.pbf"},
+	}
+
+	for _, twin := range twins {
+		t.Run(twin.XML, func(t *testing.T) {
+			xf, err := os.Open(twin.XML)
+			assert.Nil(t, err)
+			defer xf.Close()
+			xr := &cachedReader{}
+			assert.Nil(t, NewXMLDecoder(xf).Parse(xr))
+
+			pf, err := os.Open(twin.PBF)
+			assert.Nil(t, err)
+			defer pf.Close()
+			pr := &cachedReader{}
+			assert.Nil(t, NewDecoder(pf).Parse(pr))
+
+			sortCachedReader(xr)
+			sortCachedReader(pr)
+			assert.Equal(t, len(pr.Nodes), len(xr.Nodes))
+			for i := range pr.Nodes {
+				assert.Equal(t, pr.Nodes[i].ID, xr.Nodes[i].ID)
+				assert.Equal(t, pr.Nodes[i].Tags, xr.Nodes[i].Tags)
+				assert.InDelta(t, pr.Nodes[i].Lat, xr.Nodes[i].Lat, 1e-7)
+				assert.InDelta(t, pr.Nodes[i].Lon, xr.Nodes[i].Lon, 1e-7)
+			}
+			assert.Equal(t, len(pr.Ways), len(xr.Ways))
+			for i := range pr.Ways {
+				assert.Equal(t, pr.Ways[i].Element.ID, xr.Ways[i].Element.ID)
+				assert.Equal(t, pr.Ways[i].Tags, xr.Ways[i].Tags)
+				assert.Equal(t, pr.Ways[i].NodeIDs, xr.Ways[i].NodeIDs)
+			}
+			assert.Equal(t, len(pr.Rels), len(xr.Rels))
+			for i := range pr.Rels {
+				assert.Equal(t, pr.Rels[i].Element.ID, xr.Rels[i].Element.ID)
+				assert.Equal(t, pr.Rels[i].Tags, xr.Rels[i].Tags)
+				assert.Equal(t, pr.Rels[i].Members, xr.Rels[i].Members)
+			}
+		})
+	}
+}
+
+func TestXMLHistory(t *testing.T) {
+	f, err := os.Open("testdata/history.osh")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	or := &cachedReader{}
+	assert.Nil(t, NewXMLDecoderWithInfo(f).Parse(or))
+
+	assert.Len(t, or.Nodes, 4)
+	assert.Equal(t, or.Nodes[0].Info, &Info{
+		Visible:   true,
+		Timestamp: time.Date(2015, 11, 1, 19, 0, 0, 0, time.UTC),
+		UID:       1,
+		Changeset: 1,
+		Version:   1,
+		User:      "Dummy User",
+	})
+	assert.Equal(t, or.Nodes[2].Lat, 0.003)
+	assert.Equal(t, or.Nodes[2].Info.Version, 2)
+	assert.False(t, or.Nodes[3].Info.Visible)
+
+	assert.Equal(t, or.Ways[1].Info.User, "Another User")
+	assert.Equal(t, or.Ways[1].Tags, map[string]string{"name": "new line"})
+
+	assert.Equal(t, or.Rels[0].Members, []RelationMember{
+		{ID: 1, Type: NodeType},
+		{ID: 1, Type: WayType},
+	})
+	assert.False(t, or.Rels[1].Info.Visible)
+}
+
+func TestXMLWithoutInfo(t *testing.T) {
+	f, err := os.Open("testdata/base.osm")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	or := &cachedReader{}
+	assert.Nil(t, NewXMLDecoder(f).Parse(or))
+	assert.Nil(t, or.Nodes[0].Info)
+	assert.Nil(t, or.Ways[0].Info)
+}
+
+func TestXMLBadMemberType(t *testing.T) {
+	doc := `<osm><relation id="1"><member type="area" ref="1" role=""/></relation></osm>`
+	err := NewXMLDecoder(strings.NewReader(doc)).Parse(newMockOSMReader())
+	assert.NotNil(t, err)
+
+	err = NewXMLDecoder(bytes.NewReader([]byte("<osm><node id=\"1\"></osm>"))).Parse(newMockOSMReader())
+	assert.NotNil(t, err)
+}
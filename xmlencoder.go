@@ -0,0 +1,210 @@
+package gosmparse
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An XMLEncoder writes elements as OSM 0.6 XML to an output stream. Output is
+// buffered and streamed, so the memory usage does not grow with the number of
+// elements.
+type XMLEncoder struct {
+	// Generator is written into the generator attribute of the osm element.
+	Generator string
+	// Bounds is written as bounds element, if set.
+	Bounds *BoundingBox
+
+	w       *bufio.Writer
+	root    string
+	indent  string
+	started bool
+	closed  bool
+	err     error
+}
+
+// NewXMLEncoder returns a new encoder that writes to w. Generator and Bounds
+// need to be set before the first element is written.
+func NewXMLEncoder(w io.Writer) *XMLEncoder {
+	return &XMLEncoder{
+		Generator: "gosmparse",
+		w:         bufio.NewWriter(w),
+		root:      "osm",
+		indent:    "  ",
+	}
+}
+
+// WriteNode writes a node element.
+func (e *XMLEncoder) WriteNode(n Node) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.printf(`%s<node id="%d"`, e.indent, n.ID)
+	e.writeInfo(n.Info)
+	if n.Info == nil || n.Info.Visible {
+		e.printf(` lat="%s" lon="%s"`, formatCoord(n.Lat), formatCoord(n.Lon))
+	}
+	if len(n.Tags) == 0 {
+		e.printf("/>\n")
+		return e.err
+	}
+	e.printf(">\n")
+	e.writeTags(n.Tags)
+	e.printf("%s</node>\n", e.indent)
+	return e.err
+}
+
+// WriteWay writes a way element.
+func (e *XMLEncoder) WriteWay(w Way) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.printf(`%s<way id="%d"`, e.indent, w.ID)
+	e.writeInfo(w.Info)
+	if len(w.Tags) == 0 && len(w.NodeIDs) == 0 {
+		e.printf("/>\n")
+		return e.err
+	}
+	e.printf(">\n")
+	for _, id := range w.NodeIDs {
+		e.printf(`%s  <nd ref="%d"/>`+"\n", e.indent, id)
+	}
+	e.writeTags(w.Tags)
+	e.printf("%s</way>\n", e.indent)
+	return e.err
+}
+
+// WriteRelation writes a relation element.
+func (e *XMLEncoder) WriteRelation(r Relation) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.printf(`%s<relation id="%d"`, e.indent, r.ID)
+	e.writeInfo(r.Info)
+	if len(r.Tags) == 0 && len(r.Members) == 0 {
+		e.printf("/>\n")
+		return e.err
+	}
+	e.printf(">\n")
+	for _, m := range r.Members {
+		e.printf(`%s  <member type="%s" ref="%d" role="%s"/>`+"\n", e.indent, m.Type, m.ID, escapeXML(m.Role))
+	}
+	e.writeTags(r.Tags)
+	e.printf("%s</relation>\n", e.indent)
+	return e.err
+}
+
+// writeDeleted writes an element without body or location, as it is used in
+// the delete blocks of change files.
+func (e *XMLEncoder) writeDeleted(name string, el *Element) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.printf(`%s<%s id="%d"`, e.indent, name, el.ID)
+	if el.Info != nil {
+		info := *el.Info
+		info.Visible = false
+		e.writeInfo(&info)
+	}
+	e.printf("/>\n")
+	return e.err
+}
+
+// Close finishes the document and flushes all buffered data. It does not close
+// the underlying writer.
+func (e *XMLEncoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.closed = true
+	e.printf("</%s>\n", e.root)
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *XMLEncoder) start() error {
+	if e.closed {
+		return fmt.Errorf("encoder has already been closed")
+	}
+	if e.started {
+		return e.err
+	}
+	e.started = true
+	e.printf(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	e.printf(`<%s version="0.6" generator="%s">`+"\n", e.root, escapeXML(e.Generator))
+	if e.Bounds != nil {
+		e.printf(`  <bounds minlat="%s" minlon="%s" maxlat="%s" maxlon="%s"/>`+"\n",
+			formatCoord(e.Bounds.MinLat), formatCoord(e.Bounds.MinLon),
+			formatCoord(e.Bounds.MaxLat), formatCoord(e.Bounds.MaxLon))
+	}
+	return e.err
+}
+
+func (e *XMLEncoder) writeInfo(i *Info) {
+	if i == nil {
+		return
+	}
+	e.printf(` version="%d"`, i.Version)
+	if !i.Timestamp.IsZero() {
+		e.printf(` timestamp="%s"`, formatTimestamp(i.Timestamp))
+	}
+	e.printf(` changeset="%d" uid="%d" user="%s" visible="%t"`, i.Changeset, i.UID, escapeXML(i.User), i.Visible)
+}
+
+func (e *XMLEncoder) writeTags(tags map[string]string) {
+	for _, k := range sortedKeys(tags) {
+		e.printf(`%s  <tag k="%s" v="%s"/>`+"\n", e.indent, escapeXML(k), escapeXML(tags[k]))
+	}
+}
+
+func (e *XMLEncoder) printf(format string, args ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// formatCoord formats a coordinate with at most 7 decimal places, which is
+// the precision OSM uses.
+func formatCoord(c float64) string {
+	s := strconv.FormatFloat(c, 'f', 7, 64)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
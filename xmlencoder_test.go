@@ -0,0 +1,57 @@
+package gosmparse
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXMLEncoderRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/history.osh")
+	assert.Nil(t, err)
+	defer f.Close()
+	orig := &cachedReader{}
+	assert.Nil(t, NewXMLDecoderWithInfo(f).Parse(orig))
+
+	var buf bytes.Buffer
+	enc := NewXMLEncoder(&buf)
+	enc.Generator = "test"
+	enc.Bounds = &BoundingBox{MinLat: -1, MinLon: -2, MaxLat: 1, MaxLon: 2.5}
+	for _, n := range orig.Nodes {
+		assert.Nil(t, enc.WriteNode(n))
+	}
+	for _, w := range orig.Ways {
+		assert.Nil(t, enc.WriteWay(w))
+	}
+	for _, r := range orig.Rels {
+		assert.Nil(t, enc.WriteRelation(r))
+	}
+	assert.Nil(t, enc.Close())
+
+	out := buf.String()
+	assert.Contains(t, out, `<osm version="0.6" generator="test">`)
+	assert.Contains(t, out, `<bounds minlat="-1" minlon="-2" maxlat="1" maxlon="2.5"/>`)
+	assert.Contains(t, out, `<node id="1" version="1" timestamp="2015-11-01T19:00:00Z" changeset="1" uid="1" user="Dummy User" visible="true" lat="0.001" lon="0.001"/>`)
+
+	decoded := &cachedReader{}
+	assert.Nil(t, NewXMLDecoderWithInfo(&buf).Parse(decoded))
+	assert.Equal(t, orig, decoded)
+}
+
+func TestXMLEncoderEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewXMLEncoder(&buf)
+	n := Node{Element: Element{ID: 5, Tags: map[string]string{"name": `"Tom & Jerry" <3`}}, Lat: 52.5200001, Lon: -0.00000004}
+	assert.Nil(t, enc.WriteNode(n))
+	assert.Nil(t, enc.Close())
+	assert.Contains(t, buf.String(), `<tag k="name" v="&#34;Tom &amp; Jerry&#34; &lt;3"/>`)
+	assert.Contains(t, buf.String(), `lat="52.5200001" lon="0"`)
+
+	decoded := &cachedReader{}
+	assert.Nil(t, NewXMLDecoder(&buf).Parse(decoded))
+	assert.Equal(t, n.Tags, decoded.Nodes[0].Tags)
+
+	assert.NotNil(t, enc.WriteNode(n))
+}